@@ -0,0 +1,175 @@
+package pansdwan
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// XML Response Structs
+type configGetResponse struct {
+	XMLName xml.Name `xml:"response"`
+	Result  struct {
+		InnerXML string `xml:",innerxml"`
+	} `xml:"result"`
+}
+
+type configGetMember struct {
+	XMLName xml.Name `xml:"response"`
+	Result  struct {
+		Member string `xml:"member"`
+	} `xml:"result"`
+}
+
+// appliedChange is a change that has been made, and the change that undoes it.
+type appliedChange struct {
+	op   ConfigOp
+	undo *ConfigOp
+}
+
+// changeSet applies config changes one request at a time and records how to
+// undo each of them. It is used when a batch cannot be sent atomically, so a
+// failure part way through can be compensated instead of leaving half of the
+// changes on the device.
+type changeSet struct {
+	client  *APIClient
+	applied []appliedChange
+}
+
+func newChangeSet(client *APIClient) *changeSet {
+	return &changeSet{client: client}
+}
+
+// Apply snapshots the configuration at the op's xpath, then makes the change.
+func (cs *changeSet) Apply(ctx context.Context, op ConfigOp) error {
+	undo, err := cs.undoFor(ctx, op)
+	if err != nil {
+		return err
+	}
+	if _, err := cs.client.config(ctx, op.Action, op.XPath, op.Element); err != nil {
+		return err
+	}
+	cs.applied = append(cs.applied, appliedChange{op: op, undo: undo})
+	return nil
+}
+
+// undoFor returns the change restoring what is at the op's xpath now, or nil
+// when the op will not change anything that needs restoring.
+func (cs *changeSet) undoFor(ctx context.Context, op ConfigOp) (*ConfigOp, error) {
+	body, err := cs.client.Get(ctx, op.XPath)
+	if errors.Is(err, ErrObjectNotPresent) {
+		if op.Action == "delete" {
+			return nil, nil
+		}
+		undo := DeleteOp(op.XPath)
+		return &undo, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var resp configGetResponse
+	if err := decodeXML(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to snapshot %s: %w", op.XPath, err)
+	}
+	// Edit cannot create a node selected by its text, so a deleted member is
+	// set back on its list instead
+	if list, ok := memberListXPath(op.XPath); ok && op.Action == "delete" {
+		var member configGetMember
+		if err := decodeXML(body, &member); err != nil {
+			return nil, fmt.Errorf("failed to snapshot %s: %w", op.XPath, err)
+		}
+		undo := SetOp(list, memberElement(member.Result.Member))
+		return &undo, nil
+	}
+	undo := EditOp(op.XPath, strings.TrimSpace(resp.Result.InnerXML))
+	return &undo, nil
+}
+
+// memberListXPath returns the xpath of the list when xpath selects a single
+// member of it, as built by memberXPath.
+func memberListXPath(xpath string) (string, bool) {
+	i := strings.LastIndex(xpath, "/member[text()=")
+	if i < 0 || !strings.HasSuffix(xpath, "]") {
+		return "", false
+	}
+	return xpath[:i], true
+}
+
+// Rollback undoes the applied changes in reverse order. It returns the
+// changes that were reverted and those that could not be.
+func (cs *changeSet) Rollback(ctx context.Context) (reverted, failed []string) {
+	for i := len(cs.applied) - 1; i >= 0; i-- {
+		change := cs.applied[i]
+		desc := fmt.Sprintf("%s %s", change.op.Action, change.op.XPath)
+		if change.undo == nil {
+			continue
+		}
+		undo := *change.undo
+		_, err := cs.client.config(ctx, undo.Action, undo.XPath, undo.Element)
+		if err != nil {
+			tflog.Warn(ctx, "Failed to revert change", map[string]interface{}{"change": desc, "error": err.Error()})
+			failed = append(failed, fmt.Sprintf("%s (%s)", desc, err))
+			continue
+		}
+		reverted = append(reverted, desc)
+	}
+	cs.applied = nil
+	return reverted, failed
+}
+
+// applyConfigOps makes ops as one atomic multi-config batch. If the device
+// does not support multi-config the ops are made one at a time instead, and
+// if one fails the ones already made are rolled back.
+func applyConfigOps(ctx context.Context, client *APIClient, action string, ops ...ConfigOp) diag.Diagnostics {
+	if client.supportsMultiConfig() {
+		_, err := client.MultiConfig(ctx, ops...)
+		if !isMultiConfigUnsupported(err) {
+			if err != nil {
+				return diagFromErr(action, err)
+			}
+			return nil
+		}
+		tflog.Info(ctx, "Device does not support multi-config, applying changes one at a time")
+		client.disableMultiConfig()
+	}
+
+	cs := newChangeSet(client)
+	for _, op := range ops {
+		if err := cs.Apply(ctx, op); err != nil {
+			summary, detail := describeErr(action, err)
+			reverted, failed := cs.Rollback(ctx)
+			var diags diag.Diagnostics
+			diags.AddError(summary, detail+rollbackDetail(reverted, failed))
+			return diags
+		}
+	}
+	return nil
+}
+
+// rollbackDetail describes the outcome of a rollback for a diagnostic.
+func rollbackDetail(reverted, failed []string) string {
+	var detail strings.Builder
+	if len(reverted) > 0 {
+		detail.WriteString("\n\nReverted the changes already made:\n  " + strings.Join(reverted, "\n  "))
+	}
+	if len(failed) > 0 {
+		detail.WriteString("\n\nCould not revert these changes, fix them on the device before the next apply:\n  " + strings.Join(failed, "\n  "))
+	}
+	return detail.String()
+}
+
+// isMultiConfigUnsupported reports whether err is the device rejecting the
+// multi-config action itself, as PAN-OS releases without it do.
+func isMultiConfigUnsupported(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrInvalidCommand) {
+		return false
+	}
+	msg := strings.ToLower(apiErr.Message())
+	return strings.Contains(msg, "multi-config") || strings.Contains(msg, "invalid action") || strings.Contains(msg, "unknown action")
+}
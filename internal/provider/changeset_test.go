@@ -0,0 +1,155 @@
+package pansdwan
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// fakeDevice answers config gets from its candidate configuration, keyed by
+// xpath, and records every other config request it is sent. Those succeed
+// unless responses has a body for their action and xpath, such as
+// "delete /config/...", which is then returned instead.
+type fakeDevice struct {
+	mu        sync.Mutex
+	candidate map[string]string
+	responses map[string]string
+	requests  []ConfigOp
+}
+
+func (d *fakeDevice) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w.Header().Set("Content-Type", "application/xml")
+	action, xpath := r.Form.Get("action"), r.Form.Get("xpath")
+	if action == "get" {
+		result, ok := d.candidate[xpath]
+		if !ok {
+			w.Write([]byte(`<response status="success" code="7"><result/></response>`))
+			return
+		}
+		w.Write([]byte(`<response status="success" code="19"><result total-count="1" count="1">` + result + `</result></response>`))
+		return
+	}
+	d.requests = append(d.requests, ConfigOp{Action: action, XPath: xpath, Element: r.Form.Get("element")})
+	if body, ok := d.responses[action+" "+xpath]; ok {
+		w.Write([]byte(body))
+		return
+	}
+	w.Write([]byte(`<response status="success" code="20"><msg>command succeeded</msg></response>`))
+}
+
+// newFakeDevice starts a fakeDevice and returns a client for it.
+func newFakeDevice(t *testing.T, candidate map[string]string) (*fakeDevice, *APIClient) {
+	t.Helper()
+	device := &fakeDevice{candidate: candidate}
+	return device, newTestClient(t, device)
+}
+
+// newTestClient starts a server for h and returns a client for it which does
+// not retry.
+func newTestClient(t *testing.T, h http.Handler) *APIClient {
+	t.Helper()
+	server := httptest.NewTLSServer(h)
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewAPIClient(u.Host, "", "", "key", true)
+	client.MaxRetries = 0
+	return client
+}
+
+func TestChangeSetUndoFor(t *testing.T) {
+	loc := TemplateLocation("branch")
+	imports := loc.VsysImportInterfaceXPath("vsys1")
+	zone := loc.ZoneXPath("vsys1", "untrust")
+	_, client := newFakeDevice(t, map[string]string{
+		memberXPath(imports, "sdwan.901"):  `<member>sdwan.901</member>`,
+		memberXPath(imports, "sdwan.9&'1"): `<member>sdwan.9&amp;'1</member>`,
+		zone:                               `<entry name="untrust"><network><layer3><member>sdwan.901</member></layer3></network></entry>`,
+	})
+	cases := []struct {
+		name string
+		op   ConfigOp
+		want *ConfigOp
+	}{
+		{
+			name: "delete member",
+			op:   DeleteOp(memberXPath(imports, "sdwan.901")),
+			want: &ConfigOp{Action: "set", XPath: imports, Element: `<member>sdwan.901</member>`},
+		},
+		{
+			name: "delete quoted member",
+			op:   DeleteOp(memberXPath(imports, "sdwan.9&'1")),
+			want: &ConfigOp{Action: "set", XPath: imports, Element: `<member>sdwan.9&amp;&#39;1</member>`},
+		},
+		{
+			name: "delete missing member",
+			op:   DeleteOp(memberXPath(imports, "sdwan.902")),
+		},
+		{
+			name: "set missing member",
+			op:   SetOp(imports, memberElement("sdwan.902")),
+			want: &ConfigOp{Action: "delete", XPath: imports},
+		},
+		{
+			name: "edit entry",
+			op:   EditOp(zone, `<entry name="untrust"/>`),
+			want: &ConfigOp{Action: "edit", XPath: zone, Element: `<entry name="untrust"><network><layer3><member>sdwan.901</member></layer3></network></entry>`},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := newChangeSet(client).undoFor(context.Background(), tc.op)
+			if err != nil {
+				t.Fatalf("undoFor() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("undoFor() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestChangeSetRollback(t *testing.T) {
+	loc := NGFWLocation()
+	imports := loc.VsysImportInterfaceXPath("vsys1")
+	vr := loc.VirtualRouterInterfaceXPath("default")
+	device, client := newFakeDevice(t, map[string]string{
+		memberXPath(imports, "sdwan.901"): `<member>sdwan.901</member>`,
+		memberXPath(vr, "sdwan.901"):      `<member>sdwan.901</member>`,
+	})
+	ctx := context.Background()
+	cs := newChangeSet(client)
+	for _, op := range []ConfigOp{
+		DeleteOp(memberXPath(imports, "sdwan.901")),
+		DeleteOp(memberXPath(vr, "sdwan.901")),
+	} {
+		if err := cs.Apply(ctx, op); err != nil {
+			t.Fatalf("Apply(%s %s) error = %v", op.Action, op.XPath, err)
+		}
+	}
+	device.requests = nil
+
+	reverted, failed := cs.Rollback(ctx)
+	if len(reverted) != 2 || len(failed) != 0 {
+		t.Errorf("Rollback() = %v, %v, want two reverted changes", reverted, failed)
+	}
+	want := []ConfigOp{
+		{Action: "set", XPath: vr, Element: `<member>sdwan.901</member>`},
+		{Action: "set", XPath: imports, Element: `<member>sdwan.901</member>`},
+	}
+	if !reflect.DeepEqual(device.requests, want) {
+		t.Errorf("Rollback() sent %+v, want %+v", device.requests, want)
+	}
+}
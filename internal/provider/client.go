@@ -0,0 +1,296 @@
+package pansdwan
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// XML Response Structs
+type KeyGenResponse struct {
+	XMLName xml.Name `xml:"response"`
+	Text    string   `xml:",chardata"`
+	Status  string   `xml:"status,attr"`
+	Result  struct {
+		Text string `xml:",chardata"`
+		Key  string `xml:"key"`
+	} `xml:"result"`
+}
+
+// APIClient talks to the PAN-OS XML API of a Panorama or firewall. A single
+// client is shared by every resource: the API key is generated once on first
+// use and the underlying HTTP transport keeps connections alive between calls.
+type APIClient struct {
+	Host                string
+	Username            string
+	Password            string
+	SkipSSLVerification bool
+
+	// MaxRetries is the number of times a failed call is retried and
+	// MaxBackoff caps the wait between attempts.
+	MaxRetries int
+	MaxBackoff time.Duration
+
+	keyMu      sync.Mutex
+	apiKey     string
+	httpClient *http.Client
+
+	// Set once the device has rejected a multi-config batch
+	noMultiConfig atomic.Bool
+}
+
+// NewAPIClient returns a client for the given device. No request is made until
+// the first API call. When apiKey is empty a key is generated from the username
+// and password on first use.
+func NewAPIClient(host, username, password, apiKey string, skipVerify bool) *APIClient {
+	return &APIClient{
+		Host:                host,
+		Username:            username,
+		Password:            password,
+		SkipSSLVerification: skipVerify,
+		MaxRetries:          defaultMaxRetries,
+		MaxBackoff:          defaultMaxBackoff,
+		apiKey:              apiKey,
+		httpClient:          buildHttpClient(skipVerify),
+	}
+}
+
+func buildHttpClient(skipVerify bool) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			TLSClientConfig:     &tls.Config{InsecureSkipVerify: skipVerify},
+			MaxIdleConns:        10,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		Timeout: 30 * time.Second,
+	}
+}
+
+// APIKey returns the API key for the device, generating it on first use.
+func (c *APIClient) APIKey(ctx context.Context) (string, error) {
+	c.keyMu.Lock()
+	defer c.keyMu.Unlock()
+	if c.apiKey != "" {
+		return c.apiKey, nil
+	}
+	key, err := c.getAPIKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.apiKey = key
+	return key, nil
+}
+
+// resetAPIKey discards the expired key and generates a new one. If another
+// call has already replaced it, that key is reused.
+func (c *APIClient) resetAPIKey(ctx context.Context, expired string) (string, error) {
+	c.keyMu.Lock()
+	if c.apiKey == expired {
+		c.apiKey = ""
+	}
+	c.keyMu.Unlock()
+	return c.APIKey(ctx)
+}
+
+// Generate API key for PAN device
+func (c *APIClient) getAPIKey(ctx context.Context) (string, error) {
+	// Send the credentials in the request body so they never appear in a URL
+	params := url.Values{}
+	params.Set("type", "keygen")
+	params.Set("user", c.Username)
+	params.Set("password", c.Password)
+
+	// Send the request to the PAN Device
+	body, err := c.send(ctx, params, "")
+	if err != nil {
+		return "", fmt.Errorf("error making the request: %w", err)
+	}
+
+	// Parse the XML response to get the API Key
+	var keyGenResp KeyGenResponse
+	if err := decodeXML(body, &keyGenResp); err != nil {
+		return "", fmt.Errorf("error unmarshalling the XML response: %w", err)
+	}
+
+	// Check if the API key was retrieved
+	if keyGenResp.Result.Key == "" {
+		return "", fmt.Errorf("API key not found in the response")
+	}
+	// Return the API key
+	return keyGenResp.Result.Key, nil
+}
+
+// Get returns the candidate configuration found at xpath. An ErrObjectNotPresent
+// error is returned when nothing exists there.
+func (c *APIClient) Get(ctx context.Context, xpath string) ([]byte, error) {
+	body, err := c.config(ctx, "get", xpath, "")
+	if err != nil {
+		return body, err
+	}
+	// PAN-OS reports a missing object as a successful response with code 7
+	var xmlResp XMLAPIResponse
+	if err := xml.Unmarshal(body, &xmlResp); err == nil && xmlResp.Code == "7" {
+		return body, &APIError{Code: xmlResp.Code, XPath: xpath, kind: ErrObjectNotPresent}
+	}
+	return body, nil
+}
+
+// Set merges element into the candidate configuration at xpath.
+func (c *APIClient) Set(ctx context.Context, xpath, element string) ([]byte, error) {
+	return c.config(ctx, "set", xpath, element)
+}
+
+// Edit replaces the candidate configuration at xpath with element.
+func (c *APIClient) Edit(ctx context.Context, xpath, element string) ([]byte, error) {
+	return c.config(ctx, "edit", xpath, element)
+}
+
+// Delete removes the candidate configuration found at xpath.
+func (c *APIClient) Delete(ctx context.Context, xpath string) ([]byte, error) {
+	return c.config(ctx, "delete", xpath, "")
+}
+
+// Op runs an operational command, given in its XML form.
+func (c *APIClient) Op(ctx context.Context, cmd string) ([]byte, error) {
+	params := url.Values{}
+	params.Set("type", "op")
+	params.Set("cmd", cmd)
+	return c.do(ctx, params)
+}
+
+// Commit submits a commit command, given in its XML form. action is empty for
+// a Panorama commit and "all" to push to managed devices.
+func (c *APIClient) Commit(ctx context.Context, action, cmd string) ([]byte, error) {
+	params := url.Values{}
+	params.Set("type", "commit")
+	if action != "" {
+		params.Set("action", action)
+	}
+	params.Set("cmd", cmd)
+	return c.do(ctx, params)
+}
+
+// supportsMultiConfig reports whether batches can be sent with MultiConfig.
+func (c *APIClient) supportsMultiConfig() bool {
+	return !c.noMultiConfig.Load()
+}
+
+// disableMultiConfig stops MultiConfig being tried again for this client.
+func (c *APIClient) disableMultiConfig() {
+	c.noMultiConfig.Store(true)
+}
+
+func (c *APIClient) config(ctx context.Context, action, xpath, element string) ([]byte, error) {
+	params := url.Values{}
+	params.Set("type", "config")
+	params.Set("action", action)
+	params.Set("xpath", xpath)
+	if element != "" {
+		params.Set("element", element)
+	}
+	body, err := c.do(ctx, params)
+	// Record the xpath on PAN-OS errors so diagnostics can point at the object
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		apiErr.XPath = xpath
+	}
+	return body, err
+}
+
+// Endpoint returns the URL of the XML API. Parameters are always sent in the
+// request body, so this is safe to include in errors and logs.
+func (c *APIClient) Endpoint() string {
+	return fmt.Sprintf("https://%s/api/", c.Host)
+}
+
+// newRequest builds a form-encoded POST to the XML API. Credentials, xpaths and
+// config elements travel in the body rather than the query string, which keeps
+// them out of URL logs and avoids URL length limits on large elements.
+func (c *APIClient) newRequest(ctx context.Context, params url.Values) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+// do sends a request to the XML API and returns the response body. The body is
+// returned alongside the error when the device answered, so callers can inspect
+// the PAN-OS error message.
+func (c *APIClient) do(ctx context.Context, params url.Values) ([]byte, error) {
+	apiKey, err := c.APIKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	body, err := c.send(ctx, params, apiKey)
+	// A generated key can expire, so generate a fresh one and try once more
+	if errors.Is(err, ErrSessionTimedOut) && c.Username != "" && c.Password != "" {
+		if apiKey, err = c.resetAPIKey(ctx, apiKey); err != nil {
+			return nil, fmt.Errorf("failed to generate API key: %w", err)
+		}
+		body, err = c.send(ctx, params, apiKey)
+	}
+	return body, err
+}
+
+// send posts params to the XML API, retrying transient failures. Every attempt
+// is logged under the pansdwan_api subsystem with a shared correlation ID.
+func (c *APIClient) send(ctx context.Context, params url.Values, apiKey string) ([]byte, error) {
+	ctx = c.apiLogContext(ctx, apiKey)
+	correlationID := newCorrelationID()
+	return c.withRetry(ctx, func(attempt int) ([]byte, error) {
+		start := time.Now()
+		httpStatus, body, err := c.sendOnce(ctx, params, apiKey)
+		logAPICall(ctx, params, correlationID, attempt, httpStatus, body, time.Since(start), err)
+		return body, err
+	})
+}
+
+// sendOnce makes a single attempt. Errors worth retrying are wrapped in a
+// retryableError.
+func (c *APIClient) sendOnce(ctx context.Context, params url.Values, apiKey string) (int, []byte, error) {
+	req, err := c.newRequest(ctx, params)
+	if err != nil {
+		return 0, nil, err
+	}
+	if apiKey != "" {
+		req.Header.Set("X-PAN-KEY", apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if isRetryableNetError(err) {
+			return 0, nil, &retryableError{err}
+		}
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	// Read and check response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if isRetryableNetError(err) {
+			return resp.StatusCode, nil, &retryableError{err}
+		}
+		return resp.StatusCode, nil, err
+	}
+	// Catch failures in the response
+	if err := checkResponse(resp.StatusCode, resp.Header.Get("Content-Type"), body); err != nil {
+		if isRetryableStatus(resp.StatusCode) || isRetryableResponse(err) {
+			return resp.StatusCode, body, &retryableError{err}
+		}
+		return resp.StatusCode, body, err
+	}
+	return resp.StatusCode, body, nil
+}
@@ -0,0 +1,44 @@
+package pansdwan
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// XML Config Structs
+//
+// These are marshalled into the element of set and edit calls and unmarshalled
+// from the result of get calls, so what is written and what is read back go
+// through the same escaping and always agree.
+
+// sdwanInterfaceEntry is an SD-WAN interface unit under network/interface/sdwan/units.
+type sdwanInterfaceEntry struct {
+	XMLName  xml.Name `xml:"entry"`
+	Name     string   `xml:"name,attr"`
+	Protocol string   `xml:"protocol,omitempty"`
+	Comment  string   `xml:"comment,omitempty"`
+	Members  []string `xml:"interface>member"`
+}
+
+// memberList is a PAN-OS member list such as a zone's layer3 interfaces.
+type memberList struct {
+	Members []string `xml:"member"`
+}
+
+// marshalElement returns the XML of a config struct for use as an element.
+func marshalElement(v interface{}) (string, error) {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// memberElement returns a single escaped <member> element.
+func memberElement(value string) string {
+	var buf bytes.Buffer
+	buf.WriteString("<member>")
+	xml.EscapeText(&buf, []byte(value))
+	buf.WriteString("</member>")
+	return buf.String()
+}
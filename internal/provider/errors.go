@@ -0,0 +1,182 @@
+package pansdwan
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// Kinds of XML API failure. An *APIError unwraps to exactly one of these so
+// callers can branch with errors.Is.
+var (
+	ErrAuthFailed             = errors.New("authentication failed")
+	ErrInsufficientPrivileges = errors.New("insufficient privileges")
+	ErrSessionTimedOut        = errors.New("session timed out")
+	ErrBadXPath               = errors.New("bad xpath")
+	ErrObjectNotPresent       = errors.New("object not present")
+	ErrObjectExists           = errors.New("object already exists")
+	ErrReferenceCountNotZero  = errors.New("object is still referenced")
+	ErrInvalidObject          = errors.New("invalid object")
+	ErrOperationNotPossible   = errors.New("operation not possible")
+	ErrOperationDenied        = errors.New("operation denied")
+	ErrInvalidCommand         = errors.New("invalid command")
+	ErrInternal               = errors.New("internal error")
+	ErrUnexpectedResponse     = errors.New("unexpected response")
+)
+
+// Response codes documented for the PAN-OS XML API.
+var responseCodeKinds = map[string]error{
+	"400": ErrInvalidCommand,
+	"403": ErrInsufficientPrivileges,
+	"1":   ErrInvalidCommand,
+	"2":   ErrInternal,
+	"3":   ErrInternal,
+	"4":   ErrInternal,
+	"5":   ErrInternal,
+	"6":   ErrBadXPath,
+	"7":   ErrObjectNotPresent,
+	"8":   ErrObjectExists,
+	"10":  ErrReferenceCountNotZero,
+	"11":  ErrInternal,
+	"12":  ErrInvalidObject,
+	"14":  ErrOperationNotPossible,
+	"15":  ErrOperationDenied,
+	"16":  ErrInsufficientPrivileges,
+	"17":  ErrInvalidCommand,
+	"18":  ErrInvalidCommand,
+	"21":  ErrInternal,
+	"22":  ErrSessionTimedOut,
+}
+
+// Summaries and remediation hints shown in diagnostics for each kind.
+var errorKindHints = map[error]struct {
+	summary string
+	hint    string
+}{
+	ErrAuthFailed:             {"PAN-OS authentication failed", "Check the provider username/password or api_key, and that the admin account is not locked."},
+	ErrInsufficientPrivileges: {"PAN-OS admin role lacks XML API config write", "Grant the admin role XML API access for Configuration (and Operational Requests for commits)."},
+	ErrSessionTimedOut:        {"PAN-OS API session timed out", "The API key has expired. Generate a new key or configure username and password instead."},
+	ErrBadXPath:               {"PAN-OS rejected the xpath", "Check that the template, vsys and object names exist on the device."},
+	ErrObjectNotPresent:       {"PAN-OS object not present", "The object does not exist at the xpath. It may have been removed outside of Terraform."},
+	ErrObjectExists:           {"PAN-OS object already exists", "An object with the same name already exists. Import it or choose another name."},
+	ErrReferenceCountNotZero:  {"PAN-OS object is still referenced", "Remove the references listed in the message before deleting the object."},
+	ErrInvalidObject:          {"PAN-OS rejected the object as invalid", "Check the attribute values against what the PAN-OS version supports."},
+	ErrOperationNotPossible:   {"PAN-OS operation not possible", "The device cannot perform the operation in its current state."},
+	ErrOperationDenied:        {"PAN-OS operation denied", "Another administrator may hold a config lock, or the object is read-only in this location."},
+	ErrInvalidCommand:         {"PAN-OS rejected the request", "The request was malformed. This is likely a provider bug."},
+	ErrInternal:               {"PAN-OS internal error", "The device reported an internal error. Retry the apply, and check the device system logs if it persists."},
+	ErrUnexpectedResponse:     {"Unexpected PAN-OS API response", "The device did not answer with a valid XML API response."},
+}
+
+// APIError is a failure reported by the XML API.
+type APIError struct {
+	HTTPStatus int
+	Code       string
+	Lines      []string
+	XPath      string
+
+	kind error
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("PAN-OS API error: %s", e.kind)
+	if e.Code != "" {
+		msg += fmt.Sprintf(" (code %s)", e.Code)
+	}
+	if m := e.Message(); m != "" {
+		msg += ": " + m
+	}
+	return msg
+}
+
+// Unwrap returns the kind of failure, one of the Err* values.
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// Message returns the message lines from the response joined together.
+func (e *APIError) Message() string {
+	return strings.Join(e.Lines, " ")
+}
+
+// newAPIError classifies an error response from the device.
+func newAPIError(httpStatus int, code string, lines []string) *APIError {
+	apiErr := &APIError{HTTPStatus: httpStatus, Code: code, Lines: lines, kind: ErrUnexpectedResponse}
+	if kind, ok := responseCodeKinds[code]; ok {
+		apiErr.kind = kind
+	} else if httpStatus == 403 {
+		apiErr.kind = ErrInsufficientPrivileges
+	}
+	// Some failures are reported with a generic code and only the message tells them apart
+	msg := strings.ToLower(apiErr.Message())
+	switch {
+	case strings.Contains(msg, "invalid credential"):
+		apiErr.kind = ErrAuthFailed
+	case strings.Contains(msg, "cannot be deleted because of references from"):
+		apiErr.kind = ErrReferenceCountNotZero
+	case strings.Contains(msg, "already exists"):
+		apiErr.kind = ErrObjectExists
+	}
+	return apiErr
+}
+
+type XMLAPIResponse struct {
+	XMLName xml.Name `xml:"response"`
+	Status  string   `xml:"status,attr"`
+	Code    string   `xml:"code,attr"`
+	Msg     struct {
+		Text  string   `xml:",chardata"`
+		Lines []string `xml:"line"`
+	} `xml:"msg"`
+	ResultMsg string `xml:"result>msg"`
+}
+
+// lines returns the message of the response, wherever PAN-OS put it.
+func (r XMLAPIResponse) lines() []string {
+	var lines []string
+	for _, line := range r.Msg.Lines {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	for _, text := range []string{r.Msg.Text, r.ResultMsg} {
+		if text = strings.TrimSpace(text); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	return lines
+}
+
+// diagFromErr converts an API call failure into a diagnostic.
+func diagFromErr(action string, err error) diag.Diagnostics {
+	var diags diag.Diagnostics
+	diags.AddError(describeErr(action, err))
+	return diags
+}
+
+// describeErr returns the summary and detail of a diagnostic for an API call
+// failure. PAN-OS errors carry a summary for their kind, the xpath involved
+// and a remediation hint.
+func describeErr(action string, err error) (summary, detail string) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return fmt.Sprintf("%s: %s", action, err), ""
+	}
+	hint := errorKindHints[apiErr.kind]
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%s: %s", action, apiErr.Error()))
+	if apiErr.XPath != "" {
+		b.WriteString(fmt.Sprintf("\n\nXPath: %s", apiErr.XPath))
+	}
+	if hint.hint != "" {
+		b.WriteString(fmt.Sprintf("\n\n%s", hint.hint))
+	}
+	summary = hint.summary
+	if summary == "" {
+		summary = action
+	}
+	return summary, b.String()
+}
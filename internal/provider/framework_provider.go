@@ -0,0 +1,236 @@
+package pansdwan
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// The provider is served as a mux of the SDK provider in provider.go and this
+// terraform-plugin-framework provider. Resources are being moved to the
+// framework, and new ones should be written there. Terraform requires both to
+// have exactly the same provider schema, so any change to one has to be made
+// to the other.
+
+var _ provider.Provider = &frameworkProvider{}
+
+type frameworkProvider struct{}
+
+type frameworkProviderModel struct {
+	Hostname            types.String `tfsdk:"hostname"`
+	Username            types.String `tfsdk:"username"`
+	Password            types.String `tfsdk:"password"`
+	APIKey              types.String `tfsdk:"api_key"`
+	SkipSSLVerification types.Bool   `tfsdk:"skip_ssl_verification"`
+	MaxRetries          types.Int64  `tfsdk:"max_retries"`
+	RetryMaxBackoff     types.String `tfsdk:"retry_max_backoff"`
+}
+
+// NewFrameworkProvider returns the terraform-plugin-framework half of the provider.
+func NewFrameworkProvider() provider.Provider {
+	return &frameworkProvider{}
+}
+
+func (p *frameworkProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "pansdwan"
+}
+
+func (p *frameworkProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			// The SDK makes a required argument optional when its environment variable is set
+			"hostname": schema.StringAttribute{
+				Required:    os.Getenv("PANOS_HOSTNAME") == "",
+				Optional:    os.Getenv("PANOS_HOSTNAME") != "",
+				Description: "Hostname or IP address of the Panorama or firewall. Can also be set with the PANOS_HOSTNAME environment variable.",
+			},
+			"username": schema.StringAttribute{
+				Optional:    true,
+				Description: "Username used to generate an API key. Can also be set with the PANOS_USERNAME environment variable.",
+			},
+			"password": schema.StringAttribute{
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Password used to generate an API key. Can also be set with the PANOS_PASSWORD environment variable.",
+			},
+			"api_key": schema.StringAttribute{
+				Optional:    true,
+				Sensitive:   true,
+				Description: "API key to use instead of username and password. Can also be set with the PANOS_API_KEY environment variable.",
+			},
+			"skip_ssl_verification": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Skip TLS certificate verification. Can also be set with the PANOS_SKIP_VERIFY environment variable.",
+			},
+			"max_retries": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Number of times a failed API call is retried on network errors, 5xx responses and transient PAN-OS errors.",
+			},
+			"retry_max_backoff": schema.StringAttribute{
+				Optional:    true,
+				Description: "Upper bound for the exponential backoff between retries, as a duration such as \"30s\".",
+			},
+		},
+	}
+}
+
+func (p *frameworkProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var config frameworkProviderModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// Apply the environment and defaults the SDK provider gets from its schema
+	cfg := providerConfig{
+		Hostname:            stringOrEnv(config.Hostname, "PANOS_HOSTNAME"),
+		Username:            stringOrEnv(config.Username, "PANOS_USERNAME"),
+		Password:            stringOrEnv(config.Password, "PANOS_PASSWORD"),
+		APIKey:              stringOrEnv(config.APIKey, "PANOS_API_KEY"),
+		SkipSSLVerification: config.SkipSSLVerification.ValueBool(),
+		MaxRetries:          defaultMaxRetries,
+		RetryMaxBackoff:     defaultMaxBackoff.String(),
+	}
+	if config.SkipSSLVerification.IsNull() {
+		if b, err := strconv.ParseBool(os.Getenv("PANOS_SKIP_VERIFY")); err == nil {
+			cfg.SkipSSLVerification = b
+		}
+	}
+	if !config.MaxRetries.IsNull() {
+		cfg.MaxRetries = int(config.MaxRetries.ValueInt64())
+	}
+	if !config.RetryMaxBackoff.IsNull() {
+		cfg.RetryMaxBackoff = config.RetryMaxBackoff.ValueString()
+	}
+	// Configure is called before unknown values are known, there is nothing to do until then
+	if config.Hostname.IsUnknown() || config.Username.IsUnknown() || config.Password.IsUnknown() || config.APIKey.IsUnknown() {
+		return
+	}
+	client, err := cfg.client()
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid provider configuration", err.Error())
+		return
+	}
+	resp.ResourceData = client
+}
+
+func (p *frameworkProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		newSDWANInterfaceResource,
+		newZoneEntryResource,
+	}
+}
+
+func (p *frameworkProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return nil
+}
+
+// stringOrEnv returns the configured value, or the environment variable k
+// when it is not configured.
+func stringOrEnv(v types.String, k string) string {
+	if v.IsNull() {
+		return os.Getenv(k)
+	}
+	return v.ValueString()
+}
+
+// configureClient returns the client passed to a framework resource's
+// Configure, which is nil before the provider has been configured.
+func configureClient(data any, diags *diag.Diagnostics) *APIClient {
+	if data == nil {
+		return nil
+	}
+	client, ok := data.(*APIClient)
+	if !ok {
+		diags.AddError("Unexpected resource configure type", "Expected *APIClient. This is a provider bug.")
+	}
+	return client
+}
+
+// clientConfigured reports whether the provider has been configured, and
+// adds an error when it has not. The provider is left unconfigured while its
+// arguments are unknown, which Terraform allows during plan but not apply.
+func clientConfigured(client *APIClient, diags *diag.Diagnostics) bool {
+	if client == nil {
+		diags.AddError("Unconfigured provider",
+			"The provider has not been configured, as its configuration has values that are unknown until apply. Make them known before this resource is created, read, updated or deleted.")
+		return false
+	}
+	return true
+}
+
+// optionalString returns the value read from the device for an optional
+// attribute. The device has no value and "" alike, so "" is only kept when
+// that is what the state already has.
+func optionalString(prior types.String, v string) types.String {
+	if v == "" && (prior.IsNull() || prior.IsUnknown() || prior.ValueString() != "") {
+		return types.StringNull()
+	}
+	return types.StringValue(v)
+}
+
+// normalizeLocation rewrites the template and location from state written by
+// the SDK, which has "" and false for the unset values, the way the framework
+// stores them.
+func normalizeLocation(template types.String, location types.List, loc Location) (types.String, types.List) {
+	if template.ValueString() == "" {
+		template = types.StringNull()
+	}
+	if len(location.Elements()) > 0 {
+		location = locationValue(loc)
+	}
+	return template, location
+}
+
+// upgradeRawState upgrades state written by an older schema version of r
+// using upgrade, which works on the raw JSON state as SDK state upgraders do.
+// Attributes which are no longer in the schema are dropped.
+func upgradeRawState(ctx context.Context, r resource.Resource, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse, upgrade func(map[string]interface{}) (map[string]interface{}, error)) {
+	if req.RawState == nil || req.RawState.JSON == nil {
+		resp.Diagnostics.AddError("Unable to upgrade state", "The state has no JSON data to upgrade.")
+		return
+	}
+	var rawState map[string]interface{}
+	if err := json.Unmarshal(req.RawState.JSON, &rawState); err != nil {
+		resp.Diagnostics.AddError("Unable to upgrade state", err.Error())
+		return
+	}
+	rawState, err := upgrade(rawState)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to upgrade state", err.Error())
+		return
+	}
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	stateType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	for k := range rawState {
+		if _, ok := stateType.AttributeTypes[k]; !ok {
+			delete(rawState, k)
+		}
+	}
+	upgraded, err := json.Marshal(rawState)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to upgrade state", err.Error())
+		return
+	}
+	value, err := tftypes.ValueFromJSON(upgraded, stateType)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to upgrade state", err.Error())
+		return
+	}
+	dynamicValue, err := tfprotov6.NewDynamicValue(stateType, value)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to upgrade state", err.Error())
+		return
+	}
+	resp.DynamicValue = &dynamicValue
+}
@@ -0,0 +1,44 @@
+package pansdwan
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// upgradeState runs the state upgrader of r for version on the raw JSON state
+// and returns the upgraded attributes.
+func upgradeState(t *testing.T, r resource.ResourceWithUpgradeState, version int64, rawState string) map[string]tftypes.Value {
+	t.Helper()
+	ctx := context.Background()
+	req := resource.UpgradeStateRequest{RawState: &tfprotov6.RawState{JSON: []byte(rawState)}}
+	var resp resource.UpgradeStateResponse
+	r.UpgradeState(ctx)[version].StateUpgrader(ctx, req, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("upgrade state: %v", resp.Diagnostics)
+	}
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	value, err := resp.DynamicValue.Unmarshal(schemaResp.Schema.Type().TerraformType(ctx))
+	if err != nil {
+		t.Fatalf("upgraded state: %v", err)
+	}
+	var attrs map[string]tftypes.Value
+	if err := value.As(&attrs); err != nil {
+		t.Fatalf("upgraded state: %v", err)
+	}
+	return attrs
+}
+
+// stringAttr returns a string attribute of upgraded state.
+func stringAttr(t *testing.T, attrs map[string]tftypes.Value, name string) string {
+	t.Helper()
+	var s string
+	if err := attrs[name].As(&s); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return s
+}
@@ -0,0 +1,82 @@
+package pansdwan
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Resource IDs use the same colon separated form as import IDs, always with a
+// qualified location. Each segment is escaped so names containing the
+// separators can be parsed back unambiguously:
+//
+//	<location>:<name>                          pansdwan_sdwan_interface
+//	<location>:<vsys>:<zone>:<interface>       pansdwan_l3_zone_entry
+//
+// where <location> is template=<template>, template_stack=<stack> or ngfw, for
+// example `template=branch%3Aeast:vsys1:untrust:sdwan.901` for a template named "branch:east".
+
+var idEscaper = strings.NewReplacer("%", "%25", ":", "%3A", "=", "%3D")
+
+// escapeIDSegment escapes the characters used as separators in IDs.
+func escapeIDSegment(s string) string {
+	return idEscaper.Replace(s)
+}
+
+// unescapeIDSegment reverses escapeIDSegment.
+func unescapeIDSegment(s string) (string, error) {
+	return url.PathUnescape(s)
+}
+
+// formatLocationSegment returns the qualified location segment of an ID.
+func formatLocationSegment(loc Location) string {
+	switch loc.Type {
+	case LocationTemplate:
+		return "template=" + escapeIDSegment(loc.Name)
+	case LocationTemplateStack:
+		return "template_stack=" + escapeIDSegment(loc.Name)
+	default:
+		return "ngfw"
+	}
+}
+
+// buildID returns the ID of an object at loc identified by parts.
+func buildID(loc Location, parts ...string) string {
+	segments := []string{formatLocationSegment(loc)}
+	for _, part := range parts {
+		segments = append(segments, escapeIDSegment(part))
+	}
+	return strings.Join(segments, ":")
+}
+
+// sdwanInterfaceID returns the ID of an SD-WAN interface.
+func sdwanInterfaceID(loc Location, name string) string {
+	return buildID(loc, name)
+}
+
+// zoneEntryID returns the ID of a zone entry.
+func zoneEntryID(loc Location, vsys, zone, iface string) string {
+	return buildID(loc, vsys, zone, iface)
+}
+
+// locationFromRawState resolves the location from a state written before IDs
+// were structured, which has either the template argument or a location block.
+func locationFromRawState(rawState map[string]interface{}) (Location, error) {
+	if list, ok := rawState["location"].([]interface{}); ok && len(list) > 0 {
+		if block, ok := list[0].(map[string]interface{}); ok {
+			if name, _ := block["panorama_template"].(string); name != "" {
+				return TemplateLocation(name), nil
+			}
+			if name, _ := block["template_stack"].(string); name != "" {
+				return TemplateStackLocation(name), nil
+			}
+			if ngfw, _ := block["ngfw"].(bool); ngfw {
+				return NGFWLocation(), nil
+			}
+		}
+	}
+	if template, _ := rawState["template"].(string); template != "" {
+		return TemplateLocation(template), nil
+	}
+	return Location{}, fmt.Errorf("state has neither a template nor a location")
+}
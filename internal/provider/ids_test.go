@@ -0,0 +1,85 @@
+package pansdwan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEscapeIDSegment(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"sdwan.901", "sdwan.901"},
+		{"branch:east", "branch%3Aeast"},
+		{"a=b", "a%3Db"},
+		{"100%", "100%25"},
+		{"%3A", "%253A"},
+		{"branch's", "branch's"},
+		{`the "hub"`, `the "hub"`},
+		{`it's "a:b=c%"`, `it's "a%3Ab%3Dc%25"`},
+	}
+	for _, tc := range cases {
+		got := escapeIDSegment(tc.in)
+		if got != tc.want {
+			t.Errorf("escapeIDSegment(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+		if back, err := unescapeIDSegment(got); err != nil || back != tc.in {
+			t.Errorf("unescapeIDSegment(%q) = %q, %v, want %q", got, back, err, tc.in)
+		}
+	}
+}
+
+func TestSplitImportIDRoundTrip(t *testing.T) {
+	names := []string{"sdwan.901", "branch's", `the "hub"`, `it's "both"`, "branch:east", "a=b", "100%", "%3A", `'a:b="c"%'`}
+	for _, name := range names {
+		for _, loc := range []Location{TemplateLocation(name), TemplateStackLocation(name), NGFWLocation()} {
+			parts := []string{name, "untrust", name}
+			id := buildID(loc, parts...)
+			gotLoc, gotParts, err := splitImportID(id, "<location>:<vsys>:<zone>:<interface>", 3)
+			if err != nil {
+				t.Errorf("splitImportID(%q) error = %v", id, err)
+				continue
+			}
+			if gotLoc != loc || !reflect.DeepEqual(gotParts, parts) {
+				t.Errorf("splitImportID(%q) = %v, %q, want %v, %q", id, gotLoc, gotParts, loc, parts)
+			}
+		}
+	}
+}
+
+func TestSplitImportID(t *testing.T) {
+	cases := []struct {
+		id       string
+		wantLoc  Location
+		wantName string
+		wantErr  bool
+	}{
+		{id: "branch:sdwan.901", wantLoc: TemplateLocation("branch"), wantName: "sdwan.901"},
+		{id: "template=branch:sdwan.901", wantLoc: TemplateLocation("branch"), wantName: "sdwan.901"},
+		{id: "template_stack=branch%3Aeast:sdwan.901", wantLoc: TemplateStackLocation("branch:east"), wantName: "sdwan.901"},
+		{id: "ngfw:sdwan.901", wantLoc: NGFWLocation(), wantName: "sdwan.901"},
+		{id: "branch:east:sdwan.901", wantErr: true},
+		{id: "sdwan.901", wantErr: true},
+		{id: "template=:sdwan.901", wantErr: true},
+		{id: "branch:", wantErr: true},
+		{id: "branch:100%", wantErr: true},
+		{id: "vsys=vsys1:sdwan.901", wantErr: true},
+	}
+	for _, tc := range cases {
+		loc, parts, err := splitImportID(tc.id, "<location>:<name>", 1)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("splitImportID(%q) = %v, %q, want an error", tc.id, loc, parts)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitImportID(%q) error = %v", tc.id, err)
+			continue
+		}
+		if loc != tc.wantLoc || parts[0] != tc.wantName {
+			t.Errorf("splitImportID(%q) = %v, %q, want %v, %q", tc.id, loc, parts[0], tc.wantLoc, tc.wantName)
+		}
+	}
+}
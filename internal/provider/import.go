@@ -0,0 +1,66 @@
+package pansdwan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Import IDs are colon separated, starting with the location of the object:
+//
+//	<location>:<vsys>:<name>                   pansdwan_sdwan_interface
+//	<location>:<vsys>:<zone>:<interface>       pansdwan_l3_zone_entry
+//
+// where <location> is one of
+//
+//	<template>                 a Panorama template
+//	template=<template>        a Panorama template
+//	template_stack=<stack>     a Panorama template stack
+//	ngfw                       a firewall's local configuration
+//
+// For example `branch:vsys1:sdwan.901` or `template_stack=branch-stack:vsys1:untrust:sdwan.901`.
+// Segments may be escaped as in resource IDs, see ids.go.
+
+// parseLocationSegment parses the location at the start of an import ID.
+func parseLocationSegment(s string) (Location, error) {
+	kind, name, qualified := strings.Cut(s, "=")
+	if !qualified {
+		if s == "ngfw" {
+			return NGFWLocation(), nil
+		}
+		kind, name = "template", s
+	}
+	name, err := unescapeIDSegment(name)
+	if err != nil {
+		return Location{}, fmt.Errorf("location %q is not escaped correctly: %w", s, err)
+	}
+	if name == "" {
+		return Location{}, fmt.Errorf("location %q has no name", s)
+	}
+	switch kind {
+	case "template":
+		return TemplateLocation(name), nil
+	case "template_stack":
+		return TemplateStackLocation(name), nil
+	}
+	return Location{}, fmt.Errorf("unknown location type %q, expected template, template_stack or ngfw", kind)
+}
+
+// splitImportID splits an import or resource ID into its location and the
+// expected number of remaining parts, unescaping each of them.
+func splitImportID(id string, format string, count int) (Location, []string, error) {
+	fields := strings.Split(id, ":")
+	if len(fields) != count+1 {
+		return Location{}, nil, fmt.Errorf("unexpected import ID %q, expected %s", id, format)
+	}
+	loc, err := parseLocationSegment(fields[0])
+	if err != nil {
+		return Location{}, nil, fmt.Errorf("unexpected import ID %q: %w", id, err)
+	}
+	parts := fields[1:]
+	for i, field := range parts {
+		if parts[i], err = unescapeIDSegment(field); err != nil || parts[i] == "" {
+			return Location{}, nil, fmt.Errorf("unexpected import ID %q, expected %s", id, format)
+		}
+	}
+	return loc, parts, nil
+}
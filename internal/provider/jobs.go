@@ -0,0 +1,259 @@
+package pansdwan
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	defaultJobPollInterval = 5 * time.Second
+	defaultJobTimeout      = 20 * time.Minute
+)
+
+// Job is an asynchronous PAN-OS job as reported by `show jobs id`. Commits,
+// pushes, content installs, exports and log queries all run as jobs.
+type Job struct {
+	ID       string
+	Type     string
+	Status   string
+	Result   string
+	Progress int
+	Details  []string
+	Warnings []string
+	Devices  []JobDevice
+}
+
+// JobDevice is the outcome of a job on one managed device, such as a push
+// from Panorama.
+type JobDevice struct {
+	SerialNo string
+	Name     string
+	Status   string
+	Result   string
+	Details  []string
+	Warnings []string
+}
+
+// Finished reports whether the job has stopped running.
+func (j *Job) Finished() bool {
+	return j.Status == "FIN"
+}
+
+// Succeeded reports whether the job finished without failing.
+func (j *Job) Succeeded() bool {
+	return j.Finished() && j.Result == "OK"
+}
+
+// JobError is returned when a job finishes with a result other than OK.
+type JobError struct {
+	Job *Job
+}
+
+func (e *JobError) Error() string {
+	return fmt.Sprintf("job %s (%s) finished with result %s", e.Job.ID, e.Job.Type, e.Job.Result)
+}
+
+// JobWaitOptions controls how a job is polled. Zero values use the defaults.
+type JobWaitOptions struct {
+	// PollInterval is the wait between `show jobs id` calls.
+	PollInterval time.Duration
+	// Timeout bounds the whole wait, in addition to any deadline on the context.
+	Timeout time.Duration
+}
+
+// XML Response Structs
+type jobSubmitResponse struct {
+	XMLName xml.Name `xml:"response"`
+	Result  struct {
+		Job string `xml:"job"`
+	} `xml:"result"`
+}
+
+type jobLines struct {
+	Lines []string `xml:"line"`
+}
+
+type showJobResponse struct {
+	XMLName xml.Name `xml:"response"`
+	Result  struct {
+		Job struct {
+			ID       string   `xml:"id"`
+			Type     string   `xml:"type"`
+			Status   string   `xml:"status"`
+			Result   string   `xml:"result"`
+			Progress string   `xml:"progress"`
+			Details  jobLines `xml:"details"`
+			Warnings jobLines `xml:"warnings"`
+			Devices  struct {
+				Entry []struct {
+					SerialNo   string `xml:"serial-no"`
+					DeviceName string `xml:"devicename"`
+					Result     string `xml:"result"`
+					Status     string `xml:"status"`
+					Details    struct {
+						Lines    []string `xml:"line"`
+						Errors   []string `xml:"msg>errors>line"`
+						Warnings []string `xml:"msg>warnings>line"`
+					} `xml:"details"`
+				} `xml:"entry"`
+			} `xml:"devices"`
+		} `xml:"job"`
+	} `xml:"result"`
+}
+
+// jobIDFromResponse returns the job ID from a response that enqueued a job. It
+// is empty when PAN-OS had nothing to do, such as a commit with no changes.
+func jobIDFromResponse(body []byte) (string, error) {
+	var resp jobSubmitResponse
+	if err := decodeXML(body, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse job ID: %w", err)
+	}
+	return strings.TrimSpace(resp.Result.Job), nil
+}
+
+// ShowJob returns the current state of a job.
+func (c *APIClient) ShowJob(ctx context.Context, id string) (*Job, error) {
+	body, err := c.Op(ctx, fmt.Sprintf("<show><jobs><id>%s</id></jobs></show>", id))
+	if err != nil {
+		return nil, err
+	}
+	var resp showJobResponse
+	if err := decodeXML(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse job %s: %w", id, err)
+	}
+	raw := resp.Result.Job
+	job := &Job{
+		ID:       raw.ID,
+		Type:     raw.Type,
+		Status:   raw.Status,
+		Result:   raw.Result,
+		Details:  trimLines(raw.Details.Lines),
+		Warnings: trimLines(raw.Warnings.Lines),
+	}
+	if job.ID == "" {
+		job.ID = id
+	}
+	job.Progress, _ = strconv.Atoi(strings.TrimSpace(raw.Progress))
+	for _, dev := range raw.Devices.Entry {
+		job.Devices = append(job.Devices, JobDevice{
+			SerialNo: dev.SerialNo,
+			Name:     dev.DeviceName,
+			Status:   dev.Status,
+			Result:   dev.Result,
+			Details:  trimLines(append(dev.Details.Lines, dev.Details.Errors...)),
+			Warnings: trimLines(dev.Details.Warnings),
+		})
+	}
+	return job, nil
+}
+
+// WaitForJob polls a job until it finishes, the timeout passes or ctx is
+// cancelled. A job that finishes with a result other than OK is returned
+// together with a *JobError.
+func (c *APIClient) WaitForJob(ctx context.Context, id string, opts JobWaitOptions) (*Job, error) {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultJobPollInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultJobTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	for {
+		job, err := c.ShowJob(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		tflog.Debug(ctx, "Polled PAN-OS job", map[string]interface{}{
+			"job_id":   id,
+			"job_type": job.Type,
+			"status":   job.Status,
+			"progress": job.Progress,
+		})
+		if job.Finished() {
+			if !job.Succeeded() {
+				return job, &JobError{Job: job}
+			}
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return job, fmt.Errorf("waiting for job %s: %w", id, ctx.Err())
+		case <-time.After(opts.PollInterval):
+		}
+	}
+}
+
+// OpJob runs an operational command that enqueues a job and waits for it. A
+// nil job is returned when the command did not start one.
+func (c *APIClient) OpJob(ctx context.Context, cmd string, opts JobWaitOptions) (*Job, error) {
+	body, err := c.Op(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	return c.waitForSubmittedJob(ctx, body, opts)
+}
+
+// CommitJob runs a commit or push and waits for its job. A nil job is returned
+// when there was nothing to commit.
+func (c *APIClient) CommitJob(ctx context.Context, action, cmd string, opts JobWaitOptions) (*Job, error) {
+	body, err := c.Commit(ctx, action, cmd)
+	if err != nil {
+		return nil, err
+	}
+	return c.waitForSubmittedJob(ctx, body, opts)
+}
+
+func (c *APIClient) waitForSubmittedJob(ctx context.Context, body []byte, opts JobWaitOptions) (*Job, error) {
+	id, err := jobIDFromResponse(body)
+	if err != nil || id == "" {
+		return nil, err
+	}
+	return c.WaitForJob(ctx, id, opts)
+}
+
+// jobDiagnostics converts a job failure into a diagnostic listing the job
+// details and the result from every device. Warnings from a successful job
+// are returned as warning diagnostics.
+func jobDiagnostics(what string, job *Job, err error) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if err == nil {
+		if job != nil && len(job.Warnings) > 0 {
+			diags.AddWarning(fmt.Sprintf("%s job %s finished with warnings", what, job.ID), strings.Join(job.Warnings, "\n"))
+		}
+		return diags
+	}
+	if _, ok := err.(*JobError); !ok || job == nil {
+		return diagFromErr(fmt.Sprintf("Failed to %s", what), err)
+	}
+	var detail strings.Builder
+	for _, line := range append(job.Details, job.Warnings...) {
+		detail.WriteString(line + "\n")
+	}
+	for _, dev := range job.Devices {
+		detail.WriteString(fmt.Sprintf("\n%s (%s): %s %s\n", dev.Name, dev.SerialNo, dev.Status, dev.Result))
+		for _, line := range append(dev.Details, dev.Warnings...) {
+			detail.WriteString("  " + line + "\n")
+		}
+	}
+	diags.AddError(fmt.Sprintf("%s job %s finished with result %s", what, job.ID, job.Result), detail.String())
+	return diags
+}
+
+func trimLines(lines []string) []string {
+	var trimmed []string
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			trimmed = append(trimmed, line)
+		}
+	}
+	return trimmed
+}
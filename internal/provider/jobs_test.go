@@ -0,0 +1,269 @@
+package pansdwan
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Output of `show jobs id` in the form Panorama returns it, for a running
+// commit, a commit with warnings and a push which failed on one device.
+const (
+	jobRunning = `<response status="success"><result><job>
+<tenq>2024/05/01 10:00:00</tenq>
+<id>1234</id>
+<user>admin</user>
+<type>Commit</type>
+<status>ACT</status>
+<queued>NO</queued>
+<stoppable>yes</stoppable>
+<result>PEND</result>
+<tfin>Still Active</tfin>
+<description></description>
+<positionInQ>0</positionInQ>
+<progress>45</progress>
+<warnings></warnings>
+<details></details>
+</job></result></response>`
+
+	jobSucceededWithWarnings = `<response status="success"><result><job>
+<tenq>2024/05/01 10:00:00</tenq>
+<id>1234</id>
+<user>admin</user>
+<type>Commit</type>
+<status>FIN</status>
+<queued>NO</queued>
+<stoppable>no</stoppable>
+<result>OK</result>
+<tfin>2024/05/01 10:01:10</tfin>
+<description></description>
+<positionInQ>0</positionInQ>
+<progress>100</progress>
+<warnings><line>Template branch: interface sdwan.901 has no zone</line></warnings>
+<details><line>Configuration committed successfully</line></details>
+</job></result></response>`
+
+	jobPushFailed = `<response status="success"><result><job>
+<tenq>2024/05/01 10:02:00</tenq>
+<id>1235</id>
+<user>admin</user>
+<type>CommitAll</type>
+<status>FIN</status>
+<queued>NO</queued>
+<stoppable>no</stoppable>
+<result>FAIL</result>
+<tfin>2024/05/01 10:03:40</tfin>
+<description></description>
+<positionInQ>0</positionInQ>
+<progress>100</progress>
+<devices>
+<entry>
+<result>FAIL</result>
+<tfin>10:03:39</tfin>
+<devicename>fw-branch-1</devicename>
+<serial-no>012345678901</serial-no>
+<vsys>vsys1</vsys>
+<status>commit failed</status>
+<details><msg><errors>
+<line>Validation Error:</line>
+<line> vsys -> vsys1 -> zone -> untrust -> network -> layer3 'sdwan.901' is not a valid reference</line>
+</errors><warnings>
+<line>Warning: no valid license for SD-WAN</line>
+</warnings></msg></details>
+</entry>
+<entry>
+<result>OK</result>
+<tfin>10:03:35</tfin>
+<devicename>fw-branch-2</devicename>
+<serial-no>012345678902</serial-no>
+<vsys>vsys1</vsys>
+<status>commit succeeded</status>
+<details><msg><errors/><warnings/></msg></details>
+</entry>
+</devices>
+<warnings></warnings>
+<details><line>Push to template stack branch-stack failed on 1 of 2 devices</line></details>
+</job></result></response>`
+)
+
+// jobDevice answers `show jobs id` with the next of its responses, repeating
+// the last one once they run out.
+type jobDevice struct {
+	mu        sync.Mutex
+	responses []string
+	polls     int
+	polled    chan struct{}
+}
+
+func (d *jobDevice) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Form.Get("type") != "op" || !strings.Contains(r.Form.Get("cmd"), "<jobs><id>") {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	d.mu.Lock()
+	body := d.responses[min(d.polls, len(d.responses)-1)]
+	d.polls++
+	d.mu.Unlock()
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(body))
+	if d.polled != nil {
+		select {
+		case d.polled <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func TestShowJob(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		want *Job
+	}{
+		{
+			name: "running",
+			body: jobRunning,
+			want: &Job{ID: "1234", Type: "Commit", Status: "ACT", Result: "PEND", Progress: 45},
+		},
+		{
+			name: "succeeded with warnings",
+			body: jobSucceededWithWarnings,
+			want: &Job{
+				ID: "1234", Type: "Commit", Status: "FIN", Result: "OK", Progress: 100,
+				Details:  []string{"Configuration committed successfully"},
+				Warnings: []string{"Template branch: interface sdwan.901 has no zone"},
+			},
+		},
+		{
+			name: "push failed on a device",
+			body: jobPushFailed,
+			want: &Job{
+				ID: "1235", Type: "CommitAll", Status: "FIN", Result: "FAIL", Progress: 100,
+				Details: []string{"Push to template stack branch-stack failed on 1 of 2 devices"},
+				Devices: []JobDevice{
+					{
+						SerialNo: "012345678901", Name: "fw-branch-1", Status: "commit failed", Result: "FAIL",
+						Details: []string{
+							"Validation Error:",
+							"vsys -> vsys1 -> zone -> untrust -> network -> layer3 'sdwan.901' is not a valid reference",
+						},
+						Warnings: []string{"Warning: no valid license for SD-WAN"},
+					},
+					{SerialNo: "012345678902", Name: "fw-branch-2", Status: "commit succeeded", Result: "OK"},
+				},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, &jobDevice{responses: []string{tc.body}})
+			got, err := client.ShowJob(context.Background(), tc.want.ID)
+			if err != nil {
+				t.Fatalf("ShowJob() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ShowJob() =\n%+v\nwant\n%+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWaitForJob(t *testing.T) {
+	opts := JobWaitOptions{PollInterval: time.Millisecond}
+
+	t.Run("succeeds", func(t *testing.T) {
+		device := &jobDevice{responses: []string{jobRunning, jobRunning, jobSucceededWithWarnings}}
+		job, err := newTestClient(t, device).WaitForJob(context.Background(), "1234", opts)
+		if err != nil {
+			t.Fatalf("WaitForJob() error = %v", err)
+		}
+		device.mu.Lock()
+		defer device.mu.Unlock()
+		if !job.Succeeded() || device.polls != 3 {
+			t.Errorf("WaitForJob() = %+v after %d polls, want a successful job after 3", job, device.polls)
+		}
+	})
+
+	t.Run("fails", func(t *testing.T) {
+		device := &jobDevice{responses: []string{jobRunning, jobPushFailed}}
+		job, err := newTestClient(t, device).WaitForJob(context.Background(), "1235", opts)
+		var jobErr *JobError
+		if !errors.As(err, &jobErr) || jobErr.Job != job {
+			t.Fatalf("WaitForJob() error = %v, want a *JobError for the job", err)
+		}
+		if job.Result != "FAIL" {
+			t.Errorf("WaitForJob() result = %q, want FAIL", job.Result)
+		}
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		device := &jobDevice{responses: []string{jobRunning}}
+		job, err := newTestClient(t, device).WaitForJob(context.Background(), "1234",
+			JobWaitOptions{PollInterval: 5 * time.Millisecond, Timeout: 50 * time.Millisecond})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("WaitForJob() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+		// The deadline can also pass during a poll, when there is no job to return
+		if job != nil && job.Status != "ACT" {
+			t.Errorf("WaitForJob() = %+v, want the last state of the running job", job)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		device := &jobDevice{responses: []string{jobRunning}, polled: make(chan struct{}, 1)}
+		client := newTestClient(t, device)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			<-device.polled
+			cancel()
+		}()
+		start := time.Now()
+		_, err := client.WaitForJob(ctx, "1234", JobWaitOptions{PollInterval: time.Hour})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("WaitForJob() error = %v, want %v", err, context.Canceled)
+		}
+		if time.Since(start) > time.Minute {
+			t.Errorf("WaitForJob() waited out the poll interval after being cancelled")
+		}
+	})
+}
+
+func TestJobDiagnostics(t *testing.T) {
+	client := newTestClient(t, &jobDevice{responses: []string{jobSucceededWithWarnings, jobPushFailed}})
+	ctx := context.Background()
+
+	job, err := client.ShowJob(ctx, "1234")
+	diags := jobDiagnostics("commit", job, err)
+	if diags.HasError() || diags.WarningsCount() != 1 {
+		t.Fatalf("jobDiagnostics() = %v, want one warning", diags)
+	}
+	if got := diags[0].Summary(); got != "commit job 1234 finished with warnings" {
+		t.Errorf("warning summary = %q", got)
+	}
+
+	job, err = client.WaitForJob(ctx, "1235", JobWaitOptions{PollInterval: time.Millisecond})
+	diags = jobDiagnostics("push to template stack branch-stack", job, err)
+	if diags.ErrorsCount() != 1 {
+		t.Fatalf("jobDiagnostics() = %v, want one error", diags)
+	}
+	if got, want := diags[0].Summary(), "push to template stack branch-stack job 1235 finished with result FAIL"; got != want {
+		t.Errorf("error summary = %q, want %q", got, want)
+	}
+	for _, want := range []string{
+		"Push to template stack branch-stack failed on 1 of 2 devices",
+		"fw-branch-1 (012345678901): commit failed FAIL",
+		"'sdwan.901' is not a valid reference",
+		"Warning: no valid license for SD-WAN",
+		"fw-branch-2 (012345678902): commit succeeded OK",
+	} {
+		if !strings.Contains(diags[0].Detail(), want) {
+			t.Errorf("error detail does not contain %q:\n%s", want, diags[0].Detail())
+		}
+	}
+}
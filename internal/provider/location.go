@@ -0,0 +1,191 @@
+package pansdwan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LocationType is the kind of scope configuration is written into.
+type LocationType int
+
+const (
+	// LocationTemplate is a Panorama template.
+	LocationTemplate LocationType = iota
+	// LocationTemplateStack is a Panorama template stack.
+	LocationTemplateStack
+	// LocationNGFW is the local configuration of a firewall not managed through Panorama.
+	LocationNGFW
+)
+
+// Location identifies where an object lives: in a Panorama template or
+// template stack, or directly on a firewall. All xpaths used by the provider
+// are built from a Location so every scope is supported by every resource.
+type Location struct {
+	Type LocationType
+	// Name of the template or template stack. Empty for LocationNGFW.
+	Name string
+}
+
+// TemplateLocation returns the location of a Panorama template.
+func TemplateLocation(name string) Location {
+	return Location{Type: LocationTemplate, Name: name}
+}
+
+// TemplateStackLocation returns the location of a Panorama template stack.
+func TemplateStackLocation(name string) Location {
+	return Location{Type: LocationTemplateStack, Name: name}
+}
+
+// NGFWLocation returns the location of a firewall's local configuration.
+func NGFWLocation() Location {
+	return Location{Type: LocationNGFW}
+}
+
+func (l Location) String() string {
+	switch l.Type {
+	case LocationTemplate:
+		return fmt.Sprintf("template %q", l.Name)
+	case LocationTemplateStack:
+		return fmt.Sprintf("template stack %q", l.Name)
+	default:
+		return "firewall"
+	}
+}
+
+const localhostXPath = "/config/devices/entry[@name='localhost.localdomain']"
+
+// rootXPath returns the xpath of the config root for the location, under which
+// the usual devices and shared trees are found.
+func (l Location) rootXPath() string {
+	switch l.Type {
+	case LocationTemplate:
+		return localhostXPath + "/template/" + entryXPath(l.Name) + "/config"
+	case LocationTemplateStack:
+		return localhostXPath + "/template-stack/" + entryXPath(l.Name) + "/config"
+	default:
+		return "/config"
+	}
+}
+
+// StackTemplatesXPath returns the xpath of the member templates of a template
+// stack. It is only meaningful for LocationTemplateStack.
+func (l Location) StackTemplatesXPath() string {
+	return localhostXPath + "/template-stack/" + entryXPath(l.Name) + "/templates"
+}
+
+// DeviceXPath returns the xpath of the device config, which holds network and vsys settings.
+func (l Location) DeviceXPath() string {
+	return l.rootXPath() + "/devices/entry[@name='localhost.localdomain']"
+}
+
+// VsysXPath returns the xpath of a vsys.
+func (l Location) VsysXPath(vsys string) string {
+	return l.DeviceXPath() + "/vsys/" + entryXPath(vsys)
+}
+
+// VsysListXPath returns the xpath of all vsys entries.
+func (l Location) VsysListXPath() string {
+	return l.DeviceXPath() + "/vsys"
+}
+
+// SdwanInterfaceUnitsXPath returns the xpath of the SD-WAN interface units.
+func (l Location) SdwanInterfaceUnitsXPath() string {
+	return l.DeviceXPath() + "/network/interface/sdwan/units"
+}
+
+// SdwanInterfaceXPath returns the xpath of an SD-WAN interface unit.
+func (l Location) SdwanInterfaceXPath(name string) string {
+	return l.SdwanInterfaceUnitsXPath() + "/" + entryXPath(name)
+}
+
+// VsysImportInterfaceXPath returns the xpath of the interfaces imported into a vsys.
+func (l Location) VsysImportInterfaceXPath(vsys string) string {
+	return l.VsysXPath(vsys) + "/import/network/interface"
+}
+
+// ZoneXPath returns the xpath of a zone.
+func (l Location) ZoneXPath(vsys, zone string) string {
+	return l.VsysXPath(vsys) + "/zone/" + entryXPath(zone)
+}
+
+// ZoneLayer3XPath returns the xpath of the layer3 interfaces of a zone.
+func (l Location) ZoneLayer3XPath(vsys, zone string) string {
+	return l.ZoneXPath(vsys, zone) + "/network/layer3"
+}
+
+// VsysByImportInterfaceXPath returns the xpath of the names of the vsys
+// entries which import the interface, so the rest of each vsys is not fetched.
+func (l Location) VsysByImportInterfaceXPath(iface string) string {
+	return l.VsysListXPath() + "/entry[import/network/interface/member[text()=" + xpathLiteral(iface) + "]]/@name"
+}
+
+// VsysByZoneInterfaceXPath returns the xpath of the names of the vsys entries
+// which have a zone containing the layer3 interface.
+func (l Location) VsysByZoneInterfaceXPath(iface string) string {
+	return l.VsysListXPath() + "/entry[zone/entry/network/layer3/member[text()=" + xpathLiteral(iface) + "]]/@name"
+}
+
+// ZoneByInterfaceXPath returns the xpath of the names of the zones in a vsys
+// which contain the layer3 interface.
+func (l Location) ZoneByInterfaceXPath(vsys, iface string) string {
+	return l.VsysXPath(vsys) + "/zone/entry[network/layer3/member[text()=" + xpathLiteral(iface) + "]]/@name"
+}
+
+// VirtualRouterXPath returns the xpath of a virtual router.
+func (l Location) VirtualRouterXPath(vr string) string {
+	return l.DeviceXPath() + "/network/virtual-router/" + entryXPath(vr)
+}
+
+// VirtualRouterInterfaceXPath returns the xpath of the interfaces of a virtual router.
+func (l Location) VirtualRouterInterfaceXPath(vr string) string {
+	return l.VirtualRouterXPath(vr) + "/interface"
+}
+
+// VirtualRouterByInterfaceXPath returns the xpath of the names of the
+// virtual routers which have the interface.
+func (l Location) VirtualRouterByInterfaceXPath(iface string) string {
+	return l.DeviceXPath() + "/network/virtual-router/entry[interface/member[text()=" + xpathLiteral(iface) + "]]/@name"
+}
+
+// QosInterfaceXPath returns the xpath of the QoS configuration of an interface.
+func (l Location) QosInterfaceXPath(iface string) string {
+	return l.DeviceXPath() + "/network/qos/interface/" + entryXPath(iface)
+}
+
+// RuleXPath returns the xpath of a rule in one of the rulebases of a vsys,
+// such as "pbf" or "sdwan".
+func (l Location) RuleXPath(vsys, rulebase, rule string) string {
+	return l.VsysXPath(vsys) + "/rulebase/" + rulebase + "/rules/" + entryXPath(rule)
+}
+
+// memberXPath returns the xpath of a single member of a member list.
+func memberXPath(list, member string) string {
+	return list + "/member[text()=" + xpathLiteral(member) + "]"
+}
+
+// entryXPath returns the xpath step selecting an entry by name.
+func entryXPath(name string) string {
+	return "entry[@name=" + xpathLiteral(name) + "]"
+}
+
+// xpathLiteral quotes s as an XPath 1.0 string literal. XPath has no escape
+// sequences, so a value containing both quote characters is built with concat().
+func xpathLiteral(s string) string {
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+	parts := strings.Split(s, "'")
+	quoted := make([]string, 0, 2*len(parts))
+	for i, part := range parts {
+		if i > 0 {
+			quoted = append(quoted, `"'"`)
+		}
+		if part != "" {
+			quoted = append(quoted, "'"+part+"'")
+		}
+	}
+	return "concat(" + strings.Join(quoted, ", ") + ")"
+}
@@ -0,0 +1,192 @@
+package pansdwan
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// locationModel is an element of the `location` block.
+type locationModel struct {
+	PanoramaTemplate types.String `tfsdk:"panorama_template"`
+	TemplateStack    types.String `tfsdk:"template_stack"`
+	NGFW             types.Bool   `tfsdk:"ngfw"`
+}
+
+var locationAttrTypes = map[string]attr.Type{
+	"panorama_template": types.StringType,
+	"template_stack":    types.StringType,
+	"ngfw":              types.BoolType,
+}
+
+// locationBlock is the `location` block shared by every resource. Exactly one
+// scope must be chosen inside it, which validateLocationConfig checks.
+func locationBlock() schema.ListNestedBlock {
+	return schema.ListNestedBlock{
+		Description: "Where the object is configured: a Panorama template, a Panorama template stack or a firewall directly.",
+		Validators: []validator.List{
+			listvalidator.SizeAtMost(1),
+		},
+		PlanModifiers: []planmodifier.List{
+			listplanmodifier.RequiresReplaceIf(locationChanged, "", ""),
+		},
+		NestedObject: schema.NestedBlockObject{
+			Attributes: map[string]schema.Attribute{
+				"panorama_template": schema.StringAttribute{
+					Optional:    true,
+					Description: "Name of the Panorama template.",
+				},
+				"template_stack": schema.StringAttribute{
+					Optional:    true,
+					Description: "Name of the Panorama template stack.",
+				},
+				"ngfw": schema.BoolAttribute{
+					Optional:    true,
+					Description: "Set to true to configure a firewall that is not managed through Panorama.",
+				},
+			},
+		},
+	}
+}
+
+// templateAttribute is the original `template` argument, kept so existing
+// configurations continue to work.
+func templateAttribute() schema.StringAttribute {
+	return schema.StringAttribute{
+		Optional:           true,
+		DeprecationMessage: "Use location.panorama_template instead.",
+		Description:        "Name of the Panorama template.",
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplaceIf(templateChanged, "", ""),
+		},
+	}
+}
+
+// templateChanged requires replacement only when the location the template
+// resolves to changes. Moving from `template` to an equivalent location block,
+// or importing an object configured with `template`, keeps the object.
+func templateChanged(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+	var changed bool
+	changed, resp.Diagnostics = resolvedLocationChanged(ctx, req.State, req.Plan)
+	resp.RequiresReplace = changed
+}
+
+// locationChanged requires replacement only when the block resolves to a
+// different location, for the same reason as templateChanged. State written
+// by the SDK has "" and false where the framework has null.
+func locationChanged(ctx context.Context, req planmodifier.ListRequest, resp *listplanmodifier.RequiresReplaceIfFuncResponse) {
+	var changed bool
+	changed, resp.Diagnostics = resolvedLocationChanged(ctx, req.State, req.Plan)
+	resp.RequiresReplace = changed
+}
+
+// resolvedLocationChanged reports whether the template and location in the
+// plan resolve to a different location than those in the state.
+func resolvedLocationChanged(ctx context.Context, state tfsdk.State, plan tfsdk.Plan) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	var template types.String
+	var location types.List
+	diags.Append(state.GetAttribute(ctx, path.Root("template"), &template)...)
+	diags.Append(state.GetAttribute(ctx, path.Root("location"), &location)...)
+	before, _ := locationFromModel(ctx, template, location)
+	diags.Append(plan.GetAttribute(ctx, path.Root("template"), &template)...)
+	diags.Append(plan.GetAttribute(ctx, path.Root("location"), &location)...)
+	after, _ := locationFromModel(ctx, template, location)
+	return before != after, diags
+}
+
+// locationFromModel resolves the location configured on a resource.
+func locationFromModel(ctx context.Context, template types.String, location types.List) (Location, error) {
+	var blocks []locationModel
+	if !location.IsNull() && !location.IsUnknown() {
+		if diags := location.ElementsAs(ctx, &blocks, false); diags.HasError() {
+			return Location{}, fmt.Errorf("location could not be read")
+		}
+	}
+	if len(blocks) > 0 {
+		block := blocks[0]
+		switch {
+		case block.PanoramaTemplate.ValueString() != "":
+			return TemplateLocation(block.PanoramaTemplate.ValueString()), nil
+		case block.TemplateStack.ValueString() != "":
+			return TemplateStackLocation(block.TemplateStack.ValueString()), nil
+		case block.NGFW.ValueBool():
+			return NGFWLocation(), nil
+		}
+		return Location{}, fmt.Errorf("location must set exactly one of panorama_template, template_stack or ngfw = true")
+	}
+	if template.ValueString() != "" {
+		return TemplateLocation(template.ValueString()), nil
+	}
+	return Location{}, fmt.Errorf("one of location or template must be set")
+}
+
+// locationValue returns the `location` block for loc, as set by import.
+func locationValue(loc Location) types.List {
+	block := locationModel{
+		PanoramaTemplate: types.StringNull(),
+		TemplateStack:    types.StringNull(),
+		NGFW:             types.BoolNull(),
+	}
+	switch loc.Type {
+	case LocationTemplate:
+		block.PanoramaTemplate = types.StringValue(loc.Name)
+	case LocationTemplateStack:
+		block.TemplateStack = types.StringValue(loc.Name)
+	case LocationNGFW:
+		block.NGFW = types.BoolValue(true)
+	}
+	list, _ := types.ListValueFrom(context.Background(), types.ObjectType{AttrTypes: locationAttrTypes}, []locationModel{block})
+	return list
+}
+
+// validateLocationConfig checks that exactly one of template or location is
+// set, and exactly one scope inside location. Unknown values are skipped as
+// they are checked again once known.
+func validateLocationConfig(ctx context.Context, template types.String, location types.List) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if template.IsUnknown() || location.IsUnknown() {
+		return diags
+	}
+	var blocks []locationModel
+	if !location.IsNull() {
+		diags.Append(location.ElementsAs(ctx, &blocks, false)...)
+		if diags.HasError() {
+			return diags
+		}
+	}
+	if template.IsNull() == (len(blocks) == 0) {
+		diags.AddAttributeError(path.Root("location"), "Invalid location",
+			"Exactly one of template or location must be set.")
+		return diags
+	}
+	if len(blocks) == 0 {
+		return diags
+	}
+	block := blocks[0]
+	if block.PanoramaTemplate.IsUnknown() || block.TemplateStack.IsUnknown() || block.NGFW.IsUnknown() {
+		return diags
+	}
+	set := 0
+	for _, isNull := range []bool{block.PanoramaTemplate.IsNull(), block.TemplateStack.IsNull(), block.NGFW.IsNull()} {
+		if !isNull {
+			set++
+		}
+	}
+	if set != 1 {
+		diags.AddAttributeError(path.Root("location"), "Invalid location",
+			"Exactly one of panorama_template, template_stack or ngfw must be set in location.")
+	}
+	return diags
+}
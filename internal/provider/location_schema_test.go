@@ -0,0 +1,210 @@
+package pansdwan
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+var locationBlockType = tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+	"panorama_template": tftypes.String,
+	"template_stack":    tftypes.String,
+	"ngfw":              tftypes.Bool,
+}}
+
+// locationBlockValue returns a `location` block with one element, where
+// empty arguments are null.
+func locationBlockValue(template, stack string, ngfw bool) tftypes.Value {
+	block := map[string]tftypes.Value{
+		"panorama_template": tftypes.NewValue(tftypes.String, nil),
+		"template_stack":    tftypes.NewValue(tftypes.String, nil),
+		"ngfw":              tftypes.NewValue(tftypes.Bool, nil),
+	}
+	if template != "" {
+		block["panorama_template"] = tftypes.NewValue(tftypes.String, template)
+	}
+	if stack != "" {
+		block["template_stack"] = tftypes.NewValue(tftypes.String, stack)
+	}
+	if ngfw {
+		block["ngfw"] = tftypes.NewValue(tftypes.Bool, true)
+	}
+	return tftypes.NewValue(tftypes.List{ElementType: locationBlockType}, []tftypes.Value{
+		tftypes.NewValue(locationBlockType, block),
+	})
+}
+
+// resourceSchema returns the schema of r.
+func resourceSchema(r resource.Resource) schema.Schema {
+	var resp resource.SchemaResponse
+	r.Schema(context.Background(), resource.SchemaRequest{}, &resp)
+	return resp.Schema
+}
+
+// objectValue returns a value of the schema of r with attrs set and every
+// other attribute null.
+func objectValue(r resource.Resource, attrs map[string]tftypes.Value) tftypes.Value {
+	objectType := resourceSchema(r).Type().TerraformType(context.Background()).(tftypes.Object)
+	values := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+	for name, attrType := range objectType.AttributeTypes {
+		values[name] = tftypes.NewValue(attrType, nil)
+		if v, ok := attrs[name]; ok {
+			values[name] = v
+		}
+	}
+	return tftypes.NewValue(objectType, values)
+}
+
+// locationRequiresReplace runs the plan modifiers of the template argument
+// and location block and reports whether either requires replacement.
+func locationRequiresReplace(t *testing.T, r resource.Resource, stateValue, planValue tftypes.Value) bool {
+	t.Helper()
+	ctx := context.Background()
+	s := resourceSchema(r)
+	state := tfsdk.State{Schema: s, Raw: stateValue}
+	plan := tfsdk.Plan{Schema: s, Raw: planValue}
+	config := tfsdk.Config{Schema: s, Raw: planValue}
+
+	var stateTemplate, planTemplate types.String
+	state.GetAttribute(ctx, path.Root("template"), &stateTemplate)
+	plan.GetAttribute(ctx, path.Root("template"), &planTemplate)
+	templateResp := &planmodifier.StringResponse{PlanValue: planTemplate}
+	for _, m := range s.Attributes["template"].(schema.StringAttribute).PlanModifiers {
+		m.PlanModifyString(ctx, planmodifier.StringRequest{
+			Path: path.Root("template"), State: state, Plan: plan, Config: config,
+			StateValue: stateTemplate, PlanValue: planTemplate, ConfigValue: planTemplate,
+		}, templateResp)
+	}
+
+	var stateLocation, planLocation types.List
+	state.GetAttribute(ctx, path.Root("location"), &stateLocation)
+	plan.GetAttribute(ctx, path.Root("location"), &planLocation)
+	locationResp := &planmodifier.ListResponse{PlanValue: planLocation}
+	for _, m := range s.Blocks["location"].(schema.ListNestedBlock).PlanModifiers {
+		m.PlanModifyList(ctx, planmodifier.ListRequest{
+			Path: path.Root("location"), State: state, Plan: plan, Config: config,
+			StateValue: stateLocation, PlanValue: planLocation, ConfigValue: planLocation,
+		}, locationResp)
+	}
+
+	if templateResp.Diagnostics.HasError() || locationResp.Diagnostics.HasError() {
+		t.Fatalf("plan modifiers: %v %v", templateResp.Diagnostics, locationResp.Diagnostics)
+	}
+	return templateResp.RequiresReplace || locationResp.RequiresReplace
+}
+
+func TestLocationRequiresReplace(t *testing.T) {
+	str := func(s string) tftypes.Value { return tftypes.NewValue(tftypes.String, s) }
+	cases := []struct {
+		name  string
+		state map[string]tftypes.Value
+		plan  map[string]tftypes.Value
+		want  bool
+	}{
+		{
+			name:  "template to location",
+			state: map[string]tftypes.Value{"template": str("branch")},
+			plan:  map[string]tftypes.Value{"location": locationBlockValue("branch", "", false)},
+		},
+		{
+			name:  "location to template",
+			state: map[string]tftypes.Value{"location": locationBlockValue("branch", "", false)},
+			plan:  map[string]tftypes.Value{"template": str("branch")},
+		},
+		{
+			name:  "SDK state to template",
+			state: map[string]tftypes.Value{"template": str("branch"), "location": tftypes.NewValue(tftypes.List{ElementType: locationBlockType}, []tftypes.Value{})},
+			plan:  map[string]tftypes.Value{"template": str("branch")},
+		},
+		{
+			name:  "template renamed",
+			state: map[string]tftypes.Value{"template": str("branch")},
+			plan:  map[string]tftypes.Value{"template": str("hub")},
+			want:  true,
+		},
+		{
+			name:  "template to another location",
+			state: map[string]tftypes.Value{"template": str("branch")},
+			plan:  map[string]tftypes.Value{"location": locationBlockValue("hub", "", false)},
+			want:  true,
+		},
+		{
+			name:  "template to template stack",
+			state: map[string]tftypes.Value{"template": str("branch")},
+			plan:  map[string]tftypes.Value{"location": locationBlockValue("", "branch", false)},
+			want:  true,
+		},
+		{
+			name:  "template stack to ngfw",
+			state: map[string]tftypes.Value{"location": locationBlockValue("", "branch", false)},
+			plan:  map[string]tftypes.Value{"location": locationBlockValue("", "", true)},
+			want:  true,
+		},
+	}
+	for _, r := range []resource.Resource{&sdwanInterfaceResource{}, &zoneEntryResource{}} {
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				got := locationRequiresReplace(t, r, objectValue(r, tc.state), objectValue(r, tc.plan))
+				if got != tc.want {
+					t.Errorf("requires replace = %v, want %v", got, tc.want)
+				}
+			})
+		}
+	}
+}
+
+// importState runs ImportState of r for id and returns the imported state.
+func importState(t *testing.T, r resource.ResourceWithImportState, id string) map[string]tftypes.Value {
+	t.Helper()
+	ctx := context.Background()
+	s := resourceSchema(r)
+	resp := &resource.ImportStateResponse{
+		State: tfsdk.State{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)},
+	}
+	r.ImportState(ctx, resource.ImportStateRequest{ID: id}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("ImportState(%q): %v", id, resp.Diagnostics)
+	}
+	var attrs map[string]tftypes.Value
+	if err := resp.State.Raw.As(&attrs); err != nil {
+		t.Fatalf("imported state: %v", err)
+	}
+	return attrs
+}
+
+func TestImportWithTemplateRequiresNoReplace(t *testing.T) {
+	cases := []struct {
+		r  resource.ResourceWithImportState
+		id string
+	}{
+		{&sdwanInterfaceResource{}, "branch:vsys1:sdwan.901"},
+		{&sdwanInterfaceResource{}, "template=branch:sdwan.901"},
+		{&zoneEntryResource{}, "branch:vsys1:untrust:sdwan.901"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.id, func(t *testing.T) {
+			state := importState(t, tc.r, tc.id)
+			// The configuration still uses the deprecated template argument
+			plan := make(map[string]tftypes.Value, len(state))
+			for k, v := range state {
+				plan[k] = v
+			}
+			plan["template"] = tftypes.NewValue(tftypes.String, "branch")
+			delete(plan, "location")
+			if locationRequiresReplace(t, tc.r, objectValue(tc.r, state), objectValue(tc.r, plan)) {
+				t.Error("the first plan after import replaces the object")
+			}
+			plan["template"] = tftypes.NewValue(tftypes.String, "hub")
+			if !locationRequiresReplace(t, tc.r, objectValue(tc.r, state), objectValue(tc.r, plan)) {
+				t.Error("importing into a different template does not replace the object")
+			}
+		})
+	}
+}
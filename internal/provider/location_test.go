@@ -0,0 +1,47 @@
+package pansdwan
+
+import "testing"
+
+func TestXPathLiteral(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "sdwan.901", want: `'sdwan.901'`},
+		{name: "empty", in: "", want: `''`},
+		{name: "single quote", in: "branch's", want: `"branch's"`},
+		{name: "double quote", in: `the "hub"`, want: `'the "hub"'`},
+		{name: "both quotes", in: `branch's "hub"`, want: `concat('branch', "'", 's "hub"')`},
+		{name: "leading and trailing single quote", in: `'hub"'`, want: `concat("'", 'hub"', "'")`},
+		{name: "adjacent single quotes", in: `a''"b`, want: `concat('a', "'", "'", '"b')`},
+		{name: "colon", in: "branch:east", want: `'branch:east'`},
+		{name: "equals", in: "a=b", want: `'a=b'`},
+		{name: "percent", in: "100%", want: `'100%'`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := xpathLiteral(tc.in); got != tc.want {
+				t.Errorf("xpathLiteral(%q) = %s, want %s", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLocationXPath(t *testing.T) {
+	cases := []struct {
+		loc  Location
+		want string
+	}{
+		{NGFWLocation(), "/config/devices/entry[@name='localhost.localdomain']/network/interface/sdwan/units/entry[@name='sdwan.901']"},
+		{TemplateLocation("branch's"), `/config/devices/entry[@name='localhost.localdomain']/template/entry[@name="branch's"]/config/devices/entry[@name='localhost.localdomain']/network/interface/sdwan/units/entry[@name='sdwan.901']`},
+		{TemplateStackLocation("branch:east"), "/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name='branch:east']/config/devices/entry[@name='localhost.localdomain']/network/interface/sdwan/units/entry[@name='sdwan.901']"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.loc.String(), func(t *testing.T) {
+			if got := tc.loc.SdwanInterfaceXPath("sdwan.901"); got != tc.want {
+				t.Errorf("SdwanInterfaceXPath() =\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package pansdwan
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// apiLogSubsystem is the tflog subsystem every XML API call is logged under.
+// Its level can be set on its own with TF_LOG_PROVIDER_PANSDWAN_API.
+const apiLogSubsystem = "pansdwan_api"
+
+// Anything that looks like a key parameter is masked wherever it appears.
+var keyParamRegex = regexp.MustCompile(`key=[^&\s"']+`)
+
+// apiLogContext adds the API subsystem to ctx with masking for the client's
+// credentials, so nothing logged through it can leak them.
+func (c *APIClient) apiLogContext(ctx context.Context, apiKey string) context.Context {
+	ctx = tflog.NewSubsystem(ctx, apiLogSubsystem, tflog.WithLevelFromEnv("TF_LOG_PROVIDER_PANSDWAN_API"))
+	ctx = tflog.SubsystemMaskFieldValuesWithFieldKeys(ctx, apiLogSubsystem, "key", "api_key", "password", "user")
+	ctx = tflog.SubsystemMaskMessageRegexes(ctx, apiLogSubsystem, keyParamRegex)
+	ctx = tflog.SubsystemMaskAllFieldValuesRegexes(ctx, apiLogSubsystem, keyParamRegex)
+	var secrets []string
+	for _, secret := range []string{apiKey, c.Password} {
+		if secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	if len(secrets) > 0 {
+		ctx = tflog.SubsystemMaskMessageStrings(ctx, apiLogSubsystem, secrets...)
+		ctx = tflog.SubsystemMaskAllFieldValuesStrings(ctx, apiLogSubsystem, secrets...)
+	}
+	return ctx
+}
+
+// newCorrelationID returns a short random ID tying together the log entries
+// for one logical API call and its retries.
+func newCorrelationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// logAPICall writes one entry for a single attempt at an API call.
+func logAPICall(ctx context.Context, params url.Values, correlationID string, attempt int, httpStatus int, body []byte, duration time.Duration, err error) {
+	fields := map[string]interface{}{
+		"method":         "POST",
+		"type":           params.Get("type"),
+		"action":         params.Get("action"),
+		"xpath":          params.Get("xpath"),
+		"correlation_id": correlationID,
+		"attempt":        attempt + 1,
+		"duration_ms":    duration.Milliseconds(),
+	}
+	if httpStatus != 0 {
+		fields["http_status"] = httpStatus
+	}
+	var xmlResp XMLAPIResponse
+	if xml.Unmarshal(body, &xmlResp) == nil {
+		fields["panos_status"] = xmlResp.Status
+		fields["panos_code"] = xmlResp.Code
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		tflog.SubsystemWarn(ctx, apiLogSubsystem, "PAN-OS API call failed", fields)
+		return
+	}
+	tflog.SubsystemDebug(ctx, apiLogSubsystem, "PAN-OS API call", fields)
+}
@@ -0,0 +1,129 @@
+package pansdwan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+const (
+	testPassword = "Hunter2-Pa55word"
+	testKey      = "LUFRPT1firstKeyAbc123=="
+	testNewKey   = "LUFRPT1secondKeyXyz789=="
+)
+
+// keygenDevice hands out testKey and then testNewKey for the test
+// credentials, and answers other requests with the next of its op responses.
+// Every error it returns echoes the credentials it was sent.
+type keygenDevice struct {
+	mu          sync.Mutex
+	keygens     int
+	opResponses []countedResponse
+	ops         int
+}
+
+func (d *keygenDevice) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w.Header().Set("Content-Type", "application/xml")
+	if r.Form.Get("type") == "keygen" {
+		if r.Form.Get("password") != testPassword {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintf(w, `<response status="error" code="403"><result><msg>Invalid credentials for %s/%s</msg></result></response>`,
+				r.Form.Get("user"), r.Form.Get("password"))
+			return
+		}
+		key := testKey
+		if d.keygens > 0 {
+			key = testNewKey
+		}
+		d.keygens++
+		fmt.Fprintf(w, `<response status="success"><result><key>%s</key></result></response>`, key)
+		return
+	}
+	resp := d.opResponses[min(d.ops, len(d.opResponses)-1)]
+	d.ops++
+	w.WriteHeader(resp.status)
+	fmt.Fprintf(w, resp.body, r.Header.Get("X-PAN-KEY"))
+}
+
+func TestAPILogsMaskCredentials(t *testing.T) {
+	cases := []struct {
+		name        string
+		password    string
+		opResponses []countedResponse
+	}{
+		{
+			name:     "key echoed in an error",
+			password: testPassword,
+			opResponses: []countedResponse{
+				{200, `<response status="error" code="12"><msg><line>Invalid request key=%[1]s</line><line>X-PAN-KEY: %[1]s</line></msg></response>`},
+			},
+		},
+		{
+			name:     "key echoed in a retried HTML page",
+			password: testPassword,
+			opResponses: []countedResponse{
+				{502, `<html><body>Bad Gateway for %s</body></html>`},
+			},
+		},
+		{
+			name:     "key regenerated after the session timed out",
+			password: testPassword,
+			opResponses: []countedResponse{
+				{200, `<response status="error" code="22"><msg><line>Session timed out for %s</line></msg></response>`},
+				{200, `<response status="error" code="12"><msg><line>Invalid request for key %s</line></msg></response>`},
+			},
+		},
+		{
+			name:     "password echoed in a failed keygen",
+			password: "wrong-" + testPassword,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var output bytes.Buffer
+			ctx := tflogtest.RootLogger(context.Background(), &output)
+			client := newTestClient(t, &keygenDevice{opResponses: tc.opResponses})
+			client.Username, client.Password, client.apiKey = "admin", tc.password, ""
+			client.MaxRetries = 1
+			client.MaxBackoff = time.Millisecond
+
+			if _, err := client.Op(ctx, "<show><system><info/></system></show>"); err == nil {
+				t.Fatal("Op() succeeded, want the error response")
+			}
+
+			entries, err := tflogtest.MultilineJSONDecode(&output)
+			if err != nil {
+				t.Fatalf("decoding log entries: %v", err)
+			}
+			failures := 0
+			for _, entry := range entries {
+				if entry["@message"] == "PAN-OS API call failed" {
+					failures++
+				}
+				line := fmt.Sprint(entry)
+				for _, secret := range []string{testPassword, testKey, testNewKey} {
+					if strings.Contains(line, secret) {
+						t.Errorf("log entry contains %q: %v", secret, entry)
+					}
+				}
+			}
+			// Make sure the errors echoing the credentials were logged at all
+			if failures == 0 {
+				t.Errorf("no failed API calls were logged:\n%s", output.String())
+			}
+		})
+	}
+}
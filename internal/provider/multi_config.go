@@ -0,0 +1,104 @@
+package pansdwan
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// ConfigOp is one configuration change in a multi-config batch.
+type ConfigOp struct {
+	Action  string
+	XPath   string
+	Element string
+}
+
+// SetOp merges element into the configuration at xpath.
+func SetOp(xpath, element string) ConfigOp {
+	return ConfigOp{Action: "set", XPath: xpath, Element: element}
+}
+
+// EditOp replaces the configuration at xpath with element.
+func EditOp(xpath, element string) ConfigOp {
+	return ConfigOp{Action: "edit", XPath: xpath, Element: element}
+}
+
+// DeleteOp removes the configuration at xpath.
+func DeleteOp(xpath string) ConfigOp {
+	return ConfigOp{Action: "delete", XPath: xpath}
+}
+
+// XML Command Structs
+type multiConfigRequest struct {
+	XMLName xml.Name        `xml:"multi-configure-request"`
+	Ops     []multiConfigOp `xml:",any"`
+}
+
+type multiConfigOp struct {
+	XMLName xml.Name
+	ID      string `xml:"id,attr"`
+	XPath   string `xml:"xpath,attr"`
+	Element string `xml:",innerxml"`
+}
+
+// XML Response Structs
+type multiConfigResponse struct {
+	XMLName   xml.Name `xml:"response"`
+	Responses []struct {
+		ID     string `xml:"id,attr"`
+		Status string `xml:"status,attr"`
+		Code   string `xml:"code,attr"`
+		Msg    struct {
+			Lines []string `xml:"line"`
+		} `xml:"msg"`
+	} `xml:"response"`
+}
+
+// MultiConfig applies ops as a single atomic batch using the
+// `type=config&action=multi-config` API. Either every change is made or none
+// is, so a failure part way through never leaves half an object behind.
+func (c *APIClient) MultiConfig(ctx context.Context, ops ...ConfigOp) ([]byte, error) {
+	var req multiConfigRequest
+	for i, op := range ops {
+		req.Ops = append(req.Ops, multiConfigOp{
+			XMLName: xml.Name{Local: op.Action},
+			ID:      strconv.Itoa(i + 1),
+			XPath:   op.XPath,
+			Element: op.Element,
+		})
+	}
+	element, err := xml.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build multi-config request: %w", err)
+	}
+
+	params := url.Values{}
+	params.Set("type", "config")
+	params.Set("action", "multi-config")
+	params.Set("element", string(element))
+	body, err := c.do(ctx, params)
+
+	// Report the error for the change PAN-OS rejected rather than the batch
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		var resp multiConfigResponse
+		if xml.Unmarshal(body, &resp) == nil {
+			for _, r := range resp.Responses {
+				i, convErr := strconv.Atoi(r.ID)
+				if r.Status != "error" || convErr != nil || i < 1 || i > len(ops) {
+					continue
+				}
+				opErr := apiErr
+				if lines := trimLines(r.Msg.Lines); len(lines) > 0 {
+					opErr = newAPIError(apiErr.HTTPStatus, r.Code, lines)
+				}
+				opErr.XPath = ops[i-1].XPath
+				return body, opErr
+			}
+		}
+	}
+	return body, err
+}
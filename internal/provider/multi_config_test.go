@@ -0,0 +1,120 @@
+package pansdwan
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMultiConfigRequest(t *testing.T) {
+	loc := TemplateLocation(`branch's "east"`)
+	ops := []ConfigOp{
+		SetOp(loc.SdwanInterfaceUnitsXPath(), `<entry name="sdwan.901"><comment>R&amp;D</comment></entry>`),
+		EditOp(loc.ZoneLayer3XPath("vsys1", "untrust"), `<layer3><member>sdwan.901</member></layer3>`),
+		DeleteOp(memberXPath(loc.VirtualRouterInterfaceXPath("default"), "sdwan.901")),
+	}
+	device, client := newFakeDevice(t, nil)
+	if _, err := client.MultiConfig(context.Background(), ops...); err != nil {
+		t.Fatalf("MultiConfig() error = %v", err)
+	}
+	if len(device.requests) != 1 || device.requests[0].Action != "multi-config" {
+		t.Fatalf("MultiConfig() sent %+v, want one multi-config request", device.requests)
+	}
+	element := device.requests[0].Element
+
+	// Decode generically so the check does not depend on the request structs
+	var batch struct {
+		XMLName xml.Name
+		Ops     []struct {
+			XMLName xml.Name
+			ID      string `xml:"id,attr"`
+			XPath   string `xml:"xpath,attr"`
+			Inner   string `xml:",innerxml"`
+		} `xml:",any"`
+	}
+	if err := xml.Unmarshal([]byte(element), &batch); err != nil {
+		t.Fatalf("multi-config element %s: %v", element, err)
+	}
+	if batch.XMLName.Local != "multi-configure-request" || len(batch.Ops) != len(ops) {
+		t.Fatalf("multi-config element = %s, want a multi-configure-request with %d ops", element, len(ops))
+	}
+	for i, op := range ops {
+		got := batch.Ops[i]
+		if got.XMLName.Local != op.Action || got.ID != strconv.Itoa(i+1) || got.XPath != op.XPath || got.Inner != op.Element {
+			t.Errorf("op %d = <%s id=%q xpath=%q>%s, want <%s id=%q xpath=%q>%s",
+				i, got.XMLName.Local, got.ID, got.XPath, got.Inner, op.Action, strconv.Itoa(i+1), op.XPath, op.Element)
+		}
+	}
+	// The quotes in the xpath are escaped in the attribute, the elements are sent as they are
+	if strings.Contains(element, `"branch's`) || !strings.Contains(element, `<comment>R&amp;D</comment>`) {
+		t.Errorf("multi-config element is not escaped as expected: %s", element)
+	}
+}
+
+func TestMultiConfigError(t *testing.T) {
+	loc := NGFWLocation()
+	ops := []ConfigOp{
+		SetOp(loc.SdwanInterfaceUnitsXPath(), `<entry name="sdwan.901"/>`),
+		SetOp(loc.ZoneLayer3XPath("vsys1", "untrust"), memberElement("sdwan.901")),
+		SetOp(loc.VirtualRouterInterfaceXPath("default"), memberElement("sdwan.901")),
+	}
+	cases := []struct {
+		name      string
+		body      string
+		wantXPath string
+		wantLines []string
+	}{
+		{
+			name: "nested error",
+			body: `<response status="error" code="12">` +
+				`<response id="1" status="success" code="20"><msg>command succeeded</msg></response>` +
+				`<response id="2" status="error" code="12"><msg><line><![CDATA[ untrust -> network -> layer3 'sdwan.901' is not a valid reference]]></line></msg></response>` +
+				`</response>`,
+			wantXPath: ops[1].XPath,
+			wantLines: []string{"untrust -> network -> layer3 'sdwan.901' is not a valid reference"},
+		},
+		{
+			name: "nested error without a message",
+			body: `<response status="error" code="12"><msg><line>Batch failed</line></msg>` +
+				`<response id="3" status="error" code="12"/>` +
+				`</response>`,
+			wantXPath: ops[2].XPath,
+			wantLines: []string{"Batch failed"},
+		},
+		{
+			name:      "no nested response",
+			body:      `<response status="error" code="12"><msg><line>Batch failed</line></msg></response>`,
+			wantLines: []string{"Batch failed"},
+		},
+		{
+			name: "nested id out of range",
+			body: `<response status="error" code="12"><msg><line>Batch failed</line></msg>` +
+				`<response id="9" status="error" code="12"><msg><line>unknown</line></msg></response>` +
+				`</response>`,
+			wantLines: []string{"Batch failed"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			device, client := newFakeDevice(t, nil)
+			device.responses = map[string]string{"multi-config ": tc.body}
+			_, err := client.MultiConfig(context.Background(), ops...)
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("MultiConfig() error = %v, want an *APIError", err)
+			}
+			if apiErr.XPath != tc.wantXPath {
+				t.Errorf("XPath = %q, want %q", apiErr.XPath, tc.wantXPath)
+			}
+			if strings.Join(apiErr.Lines, "\n") != strings.Join(tc.wantLines, "\n") {
+				t.Errorf("Lines = %q, want %q", apiErr.Lines, tc.wantLines)
+			}
+			if !errors.Is(err, ErrInvalidObject) {
+				t.Errorf("MultiConfig() error kind = %v, want %v", apiErr.kind, ErrInvalidObject)
+			}
+		})
+	}
+}
@@ -2,10 +2,9 @@ package pansdwan
 
 import (
 	"context"
-	"crypto/tls"
-	"encoding/xml"
 	"fmt"
-	"net/http"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
@@ -16,74 +15,123 @@ func Provider() *schema.Provider {
 	return &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			"hostname": {
-				Type:     schema.TypeString,
-				Required: true,
+				Type:        schema.TypeString,
+				Required:    true,
+				DefaultFunc: schema.EnvDefaultFunc("PANOS_HOSTNAME", nil),
+				Description: "Hostname or IP address of the Panorama or firewall. Can also be set with the PANOS_HOSTNAME environment variable.",
 			},
 			"username": {
-				Type:     schema.TypeString,
-				Required: true,
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("PANOS_USERNAME", nil),
+				Description: "Username used to generate an API key. Can also be set with the PANOS_USERNAME environment variable.",
 			},
 			"password": {
-				Type:      schema.TypeString,
-				Required:  true,
-				Sensitive: true,
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("PANOS_PASSWORD", nil),
+				Description: "Password used to generate an API key. Can also be set with the PANOS_PASSWORD environment variable.",
+			},
+			"api_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("PANOS_API_KEY", nil),
+				Description: "API key to use instead of username and password. Can also be set with the PANOS_API_KEY environment variable.",
 			},
 			"skip_ssl_verification": {
-				Type:      schema.TypeBool,
-				Optional:  true,
-				Sensitive: false,
-				Default:   false,
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Sensitive:   false,
+				DefaultFunc: envBoolDefaultFunc("PANOS_SKIP_VERIFY", false),
+				Description: "Skip TLS certificate verification. Can also be set with the PANOS_SKIP_VERIFY environment variable.",
+			},
+			"max_retries": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     defaultMaxRetries,
+				Description: "Number of times a failed API call is retried on network errors, 5xx responses and transient PAN-OS errors.",
+			},
+			"retry_max_backoff": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     defaultMaxBackoff.String(),
+				Description: "Upper bound for the exponential backoff between retries, as a duration such as \"30s\".",
 			},
 		},
+		// pansdwan_sdwan_interface and pansdwan_l3_zone_entry are served by
+		// the framework provider, see framework_provider.go
 		ResourcesMap: map[string]*schema.Resource{
-			"pansdwan_sdwan_interface": resourceSDWANInterface(),
-			"pansdwan_l3_zone_entry":   resourceZoneEntry(),
+			"pansdwan_commit": resourceCommit(),
 		},
 		ConfigureContextFunc: providerConfigure,
 	}
 }
 
-type XMLAPIResponse struct {
-	XMLName xml.Name `xml:"response"`
-	Status  string   `xml:"status,attr"`
-	Code    string   `xml:"code,attr"`
-	Msg     struct {
-		Lines []string `xml:"line"`
-	} `xml:"msg"`
+func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+	client, err := providerConfig{
+		Hostname:            d.Get("hostname").(string),
+		Username:            d.Get("username").(string),
+		Password:            d.Get("password").(string),
+		APIKey:              d.Get("api_key").(string),
+		SkipSSLVerification: d.Get("skip_ssl_verification").(bool),
+		MaxRetries:          d.Get("max_retries").(int),
+		RetryMaxBackoff:     d.Get("retry_max_backoff").(string),
+	}.client()
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+	return client, nil
 }
 
-type APIClient struct {
-	Host                string
+// providerConfig is the provider configuration with the environment and
+// defaults applied. The SDK and framework providers both build their client
+// from it so they behave the same.
+type providerConfig struct {
+	Hostname            string
 	Username            string
 	Password            string
+	APIKey              string
 	SkipSSLVerification bool
+	MaxRetries          int
+	RetryMaxBackoff     string
 }
 
-func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
-	return &APIClient{
-		Host:                d.Get("hostname").(string),
-		Username:            d.Get("username").(string),
-		Password:            d.Get("password").(string),
-		SkipSSLVerification: d.Get("skip_ssl_verification").(bool),
-	}, nil
-}
-
-func buildHttpClient(skipVerify bool) *http.Client {
-	return &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: skipVerify},
-		},
-		Timeout: 30 * time.Second,
+// client validates the configuration and returns a client for it.
+func (cfg providerConfig) client() (*APIClient, error) {
+	if cfg.Hostname == "" {
+		return nil, fmt.Errorf("hostname must be configured")
 	}
+	// Exactly one way of authenticating must be configured, either from HCL or the environment
+	if cfg.APIKey != "" && (cfg.Username != "" || cfg.Password != "") {
+		return nil, fmt.Errorf("Only one of api_key or username/password can be configured")
+	}
+	if cfg.APIKey == "" && (cfg.Username == "" || cfg.Password == "") {
+		return nil, fmt.Errorf("Either api_key or both username and password must be configured")
+	}
+	maxBackoff, err := time.ParseDuration(cfg.RetryMaxBackoff)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid retry_max_backoff: %s", err)
+	}
+	if cfg.MaxRetries < 0 {
+		return nil, fmt.Errorf("max_retries cannot be negative")
+	}
+	client := NewAPIClient(cfg.Hostname, cfg.Username, cfg.Password, cfg.APIKey, cfg.SkipSSLVerification)
+	client.MaxRetries = cfg.MaxRetries
+	client.MaxBackoff = maxBackoff
+	return client, nil
 }
 
-func checkXMLResponse(body []byte) error {
-	var xmlResp XMLAPIResponse
-	if err := xml.Unmarshal(body, &xmlResp); err != nil {
-		return fmt.Errorf("failed to unmarshal XML: %w. Response: %s", err, xmlResp)
-	}
-	if xmlResp.Status == "error" {
-		return fmt.Errorf("PAN-OS API error: code=%s, message=%s. Response: %s", xmlResp.Code, xmlResp.Msg, xmlResp)
+// envBoolDefaultFunc reads a boolean from the environment, falling back to dv
+// when the variable is unset or cannot be parsed.
+func envBoolDefaultFunc(k string, dv bool) schema.SchemaDefaultFunc {
+	return func() (interface{}, error) {
+		if v := os.Getenv(k); v != "" {
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b, nil
+			}
+		}
+		return dv, nil
 	}
-	return nil
 }
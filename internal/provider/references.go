@@ -0,0 +1,264 @@
+package pansdwan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A reference error lists every object still using the interface as a path
+// through the configuration, one per message line:
+//
+//	sdwan.901 cannot be deleted because of references from:
+//	 vsys -> vsys1 -> import -> network -> interface
+//	 vsys -> vsys1 -> zone -> untrust -> network -> layer3
+//	 network -> virtual-router -> default -> interface
+//
+// In a Panorama template the paths start at the template instead, as in
+// "template -> branch -> config -> devices -> localhost.localdomain -> network -> ...".
+// Each kind of path is recognised by a referenceHandler, which also knows how
+// to detach the interface from that kind of object.
+
+// Types of object which reference an interface
+const (
+	refVsysImport    = "vsys import"
+	refZone          = "zone"
+	refVirtualRouter = "virtual router"
+	refStaticRoute   = "static route"
+	refBGPPeer       = "BGP peer"
+	refQosInterface  = "QoS interface"
+	refPBFRule       = "PBF rule"
+	refPBFRuleEgress = "PBF rule egress"
+	refSdwanRule     = "SD-WAN policy rule"
+	refUnknown       = "unrecognised reference"
+)
+
+// interfaceReference is an object which references an interface.
+type interfaceReference struct {
+	// Type is the kind of object, one of the ref* values
+	Type string
+	// Location is the vsys or virtual router the object is in, if any
+	Location string
+	// Name is the name of the object
+	Name string
+
+	// captures are the names matched by the handler's pattern, which it needs
+	// to build the xpath of the object
+	captures []string
+	handler  *referenceHandler
+}
+
+func (r interfaceReference) String() string {
+	if r.Location != "" {
+		return fmt.Sprintf("%s %s in %s", r.Type, r.Name, r.Location)
+	}
+	return fmt.Sprintf("%s %s", r.Type, r.Name)
+}
+
+// detach returns the change removing the interface from the referencing
+// object, or an error when that cannot be done without breaking the object.
+func (r interfaceReference) detach(iface string, loc Location) (ConfigOp, error) {
+	if r.handler == nil || r.handler.detach == nil {
+		return ConfigOp{}, fmt.Errorf("%s cannot be detached automatically", r)
+	}
+	return r.handler.detach(r, iface, loc)
+}
+
+// ownedBy reports whether the reference is the vsys, virtual router or zone
+// set on the resource.
+func (r interfaceReference) ownedBy(vsys, vr, zone string) bool {
+	switch r.Type {
+	case refVsysImport:
+		return r.Name == vsys
+	case refVirtualRouter:
+		return r.Name == vr
+	case refZone:
+		return r.Location == vsys && r.Name == zone
+	}
+	return false
+}
+
+// referenceHandler recognises one type of reference path and detaches the
+// interface from that type of object.
+type referenceHandler struct {
+	// pattern is matched against the path steps, "*" matches and captures any
+	// one step and a trailing "**" matches and captures the rest of the path
+	pattern []string
+	// reference builds the reference from the captured steps
+	reference func(captures []string) interfaceReference
+	// detach is nil for references which cannot be removed without leaving
+	// the object invalid
+	detach func(ref interfaceReference, iface string, loc Location) (ConfigOp, error)
+}
+
+// referenceHandlers are tried in order, so more specific patterns come first.
+var referenceHandlers = []*referenceHandler{
+	// vsys -> vsys1 -> import -> network -> interface
+	{
+		pattern: []string{"vsys", "*", "import", "network", "interface"},
+		reference: func(c []string) interfaceReference {
+			return interfaceReference{Type: refVsysImport, Name: c[0]}
+		},
+		detach: func(r interfaceReference, iface string, loc Location) (ConfigOp, error) {
+			return removeInterfaceFromVsys(iface, loc, r.captures[0]), nil
+		},
+	},
+	// vsys -> vsys1 -> zone -> untrust -> network -> layer3
+	{
+		pattern: []string{"vsys", "*", "zone", "*", "network", "*"},
+		reference: func(c []string) interfaceReference {
+			return interfaceReference{Type: refZone, Location: c[0], Name: c[1]}
+		},
+		detach: func(r interfaceReference, iface string, loc Location) (ConfigOp, error) {
+			return DeleteOp(memberXPath(loc.ZoneXPath(r.captures[0], r.captures[1])+"/network/"+r.captures[2], iface)), nil
+		},
+	},
+	// network -> virtual-router -> default -> interface
+	{
+		pattern: []string{"network", "virtual-router", "*", "interface"},
+		reference: func(c []string) interfaceReference {
+			return interfaceReference{Type: refVirtualRouter, Name: c[0]}
+		},
+		detach: func(r interfaceReference, iface string, loc Location) (ConfigOp, error) {
+			return removeInterfaceFromVr(iface, loc, r.captures[0]), nil
+		},
+	},
+	// network -> virtual-router -> default -> routing-table -> ip -> static-route -> to-hub -> interface
+	{
+		pattern: []string{"network", "virtual-router", "*", "routing-table", "*", "static-route", "*", "interface"},
+		reference: func(c []string) interfaceReference {
+			// The routing table is part of the location so ip and ipv6 routes of the same name are told apart
+			return interfaceReference{Type: refStaticRoute, Location: c[0] + " " + c[1], Name: c[2]}
+		},
+		// The route keeps its next hop, only the egress interface is removed
+		detach: func(r interfaceReference, iface string, loc Location) (ConfigOp, error) {
+			return DeleteOp(loc.VirtualRouterXPath(r.captures[0]) + "/routing-table/" + r.captures[1] + "/static-route/" + entryXPath(r.captures[2]) + "/interface"), nil
+		},
+	},
+	// network -> virtual-router -> default -> protocol -> bgp -> peer-group -> hubs -> peer -> hub1 -> local-address -> interface
+	{
+		pattern: []string{"network", "virtual-router", "*", "protocol", "bgp", "peer-group", "*", "peer", "*", "local-address", "interface"},
+		reference: func(c []string) interfaceReference {
+			return interfaceReference{Type: refBGPPeer, Location: c[0], Name: c[1] + "/" + c[2]}
+		},
+		// A peer cannot be left without a local address, so it has to be changed by hand
+	},
+	// network -> qos -> interface -> sdwan.901
+	{
+		pattern: []string{"network", "qos", "interface", "*"},
+		reference: func(c []string) interfaceReference {
+			return interfaceReference{Type: refQosInterface, Name: c[0]}
+		},
+		detach: func(r interfaceReference, iface string, loc Location) (ConfigOp, error) {
+			return DeleteOp(loc.QosInterfaceXPath(r.captures[0])), nil
+		},
+	},
+	// vsys -> vsys1 -> rulebase -> pbf -> rules -> to-hub -> action -> forward -> egress-interface
+	{
+		pattern: []string{"vsys", "*", "rulebase", "pbf", "rules", "*", "action", "**"},
+		reference: func(c []string) interfaceReference {
+			return interfaceReference{Type: refPBFRuleEgress, Location: c[0], Name: c[1]}
+		},
+		// A forwarding rule cannot be left without an egress interface, so it has to be changed by hand
+	},
+	// vsys -> vsys1 -> rulebase -> pbf -> rules -> from-branch -> from -> interface
+	{
+		pattern: []string{"vsys", "*", "rulebase", "pbf", "rules", "*", "**"},
+		reference: func(c []string) interfaceReference {
+			return interfaceReference{Type: refPBFRule, Location: c[0], Name: c[1]}
+		},
+		detach: detachRuleMember("pbf"),
+	},
+	// vsys -> vsys1 -> rulebase -> sdwan -> rules -> voice -> ...
+	{
+		pattern: []string{"vsys", "*", "rulebase", "sdwan", "rules", "*", "**"},
+		reference: func(c []string) interfaceReference {
+			return interfaceReference{Type: refSdwanRule, Location: c[0], Name: c[1]}
+		},
+		detach: detachRuleMember("sdwan"),
+	},
+}
+
+// detachRuleMember returns a detach function removing the interface from the
+// member list of a rule the path ends at, such as a PBF rule's source interfaces.
+func detachRuleMember(rulebase string) func(r interfaceReference, iface string, loc Location) (ConfigOp, error) {
+	return func(r interfaceReference, iface string, loc Location) (ConfigOp, error) {
+		field := r.captures[2]
+		if field == "" {
+			return ConfigOp{}, fmt.Errorf("%s cannot be detached automatically", r)
+		}
+		return DeleteOp(memberXPath(loc.RuleXPath(r.captures[0], rulebase, r.captures[1])+"/"+field, iface)), nil
+	}
+}
+
+// match returns the captured steps if path matches the handler's pattern.
+func (h *referenceHandler) match(path []string) ([]string, bool) {
+	var captures []string
+	for i, step := range h.pattern {
+		if step == "**" && i <= len(path) {
+			return append(captures, strings.Join(path[i:], "/")), true
+		}
+		if i >= len(path) {
+			return nil, false
+		}
+		switch step {
+		case "*":
+			captures = append(captures, path[i])
+		default:
+			if path[i] != step {
+				return nil, false
+			}
+		}
+	}
+	return captures, len(path) == len(h.pattern)
+}
+
+// parseReferencePath splits a reference line into its path steps, dropping
+// the template prefix so every location reports the same paths.
+func parseReferencePath(line string) []string {
+	if _, after, found := strings.Cut(line, "references from:"); found {
+		line = after
+	}
+	line = strings.TrimSuffix(strings.TrimSpace(line), ".")
+	if !strings.Contains(line, "->") {
+		return nil
+	}
+	var path []string
+	for _, step := range strings.Split(line, "->") {
+		path = append(path, strings.TrimSpace(step))
+	}
+	for i, step := range path {
+		if step == "localhost.localdomain" {
+			return path[i+1:]
+		}
+	}
+	return path
+}
+
+// parseInterfaceReferences returns every reference listed in the message
+// lines of a reference error, once each. Paths no handler recognises are
+// returned as refUnknown so they are still reported.
+func parseInterfaceReferences(lines []string) []interfaceReference {
+	var refs []interfaceReference
+	seen := map[string]bool{}
+	for _, line := range lines {
+		path := parseReferencePath(line)
+		if path == nil {
+			continue
+		}
+		ref := interfaceReference{Type: refUnknown, Name: strings.Join(path, " -> ")}
+		for _, h := range referenceHandlers {
+			if captures, ok := h.match(path); ok {
+				ref = h.reference(captures)
+				ref.captures = captures
+				ref.handler = h
+				break
+			}
+		}
+		// Key on the path rather than the description, which leaves out parts such as the routing table
+		if key := strings.Join(path, "/"); !seen[key] {
+			seen[key] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
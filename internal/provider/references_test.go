@@ -0,0 +1,224 @@
+package pansdwan
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// referenceError is a reference error as PAN-OS returns it when deleting
+// sdwan.901, with one message line per path.
+func referenceError(paths ...string) string {
+	var body strings.Builder
+	body.WriteString(`<response status="error" code="12"><msg>`)
+	body.WriteString(`<line><![CDATA[ sdwan.901 cannot be deleted because of references from:]]></line>`)
+	for _, p := range paths {
+		body.WriteString(`<line><![CDATA[ ` + p + `]]></line>`)
+	}
+	body.WriteString(`</msg></response>`)
+	return body.String()
+}
+
+// referenceLines returns the message lines of body as the client reports them.
+func referenceLines(t *testing.T, body string) []string {
+	t.Helper()
+	var apiErr *APIError
+	if err := checkResponse(200, "application/xml", []byte(body)); !errors.As(err, &apiErr) {
+		t.Fatalf("checkResponse() = %v, want an *APIError", err)
+	}
+	if !errors.Is(apiErr, ErrReferenceCountNotZero) {
+		t.Fatalf("checkResponse() kind = %v, want %v", apiErr.kind, ErrReferenceCountNotZero)
+	}
+	return apiErr.Lines
+}
+
+func TestParseInterfaceReferences(t *testing.T) {
+	ngfw := "/config/devices/entry[@name='localhost.localdomain']"
+	cases := []struct {
+		name     string
+		path     string
+		refType  string
+		location string
+		refName  string
+		// xpath is what detach deletes below the device, empty when the
+		// reference cannot be detached
+		xpath string
+	}{
+		{
+			name:    "vsys import",
+			path:    "vsys -> vsys1 -> import -> network -> interface",
+			refType: refVsysImport,
+			refName: "vsys1",
+			xpath:   "/vsys/entry[@name='vsys1']/import/network/interface/member[text()='sdwan.901']",
+		},
+		{
+			name:     "zone",
+			path:     "vsys -> vsys1 -> zone -> untrust -> network -> layer3",
+			refType:  refZone,
+			location: "vsys1",
+			refName:  "untrust",
+			xpath:    "/vsys/entry[@name='vsys1']/zone/entry[@name='untrust']/network/layer3/member[text()='sdwan.901']",
+		},
+		{
+			name:     "second zone",
+			path:     "vsys -> vsys2 -> zone -> sdwan-hub -> network -> layer3",
+			refType:  refZone,
+			location: "vsys2",
+			refName:  "sdwan-hub",
+			xpath:    "/vsys/entry[@name='vsys2']/zone/entry[@name='sdwan-hub']/network/layer3/member[text()='sdwan.901']",
+		},
+		{
+			name:    "virtual router",
+			path:    "network -> virtual-router -> default -> interface",
+			refType: refVirtualRouter,
+			refName: "default",
+			xpath:   "/network/virtual-router/entry[@name='default']/interface/member[text()='sdwan.901']",
+		},
+		{
+			name:     "ipv4 static route",
+			path:     "network -> virtual-router -> default -> routing-table -> ip -> static-route -> to-hub -> interface",
+			refType:  refStaticRoute,
+			location: "default ip",
+			refName:  "to-hub",
+			xpath:    "/network/virtual-router/entry[@name='default']/routing-table/ip/static-route/entry[@name='to-hub']/interface",
+		},
+		{
+			name:     "ipv6 static route",
+			path:     "network -> virtual-router -> default -> routing-table -> ipv6 -> static-route -> to-hub -> interface",
+			refType:  refStaticRoute,
+			location: "default ipv6",
+			refName:  "to-hub",
+			xpath:    "/network/virtual-router/entry[@name='default']/routing-table/ipv6/static-route/entry[@name='to-hub']/interface",
+		},
+		{
+			name:     "BGP peer",
+			path:     "network -> virtual-router -> default -> protocol -> bgp -> peer-group -> hubs -> peer -> hub1 -> local-address -> interface",
+			refType:  refBGPPeer,
+			location: "default",
+			refName:  "hubs/hub1",
+		},
+		{
+			name:    "QoS interface",
+			path:    "network -> qos -> interface -> sdwan.901",
+			refType: refQosInterface,
+			refName: "sdwan.901",
+			xpath:   "/network/qos/interface/entry[@name='sdwan.901']",
+		},
+		{
+			name:     "PBF rule egress",
+			path:     "vsys -> vsys1 -> rulebase -> pbf -> rules -> to-hub -> action -> forward -> egress-interface",
+			refType:  refPBFRuleEgress,
+			location: "vsys1",
+			refName:  "to-hub",
+		},
+		{
+			name:     "PBF rule source",
+			path:     "vsys -> vsys1 -> rulebase -> pbf -> rules -> from-branch -> from -> interface",
+			refType:  refPBFRule,
+			location: "vsys1",
+			refName:  "from-branch",
+			xpath:    "/vsys/entry[@name='vsys1']/rulebase/pbf/rules/entry[@name='from-branch']/from/interface/member[text()='sdwan.901']",
+		},
+		{
+			name:     "SD-WAN policy rule",
+			path:     "vsys -> vsys1 -> rulebase -> sdwan -> rules -> voice -> from -> interface",
+			refType:  refSdwanRule,
+			location: "vsys1",
+			refName:  "voice",
+			xpath:    "/vsys/entry[@name='vsys1']/rulebase/sdwan/rules/entry[@name='voice']/from/interface/member[text()='sdwan.901']",
+		},
+		{
+			name:     "SD-WAN policy rule without a field",
+			path:     "vsys -> vsys1 -> rulebase -> sdwan -> rules -> voice",
+			refType:  refSdwanRule,
+			location: "vsys1",
+			refName:  "voice",
+		},
+		{
+			name:    "unknown",
+			path:    "network -> tunnel -> ipsec -> to-hub -> tunnel-interface",
+			refType: refUnknown,
+			refName: "network -> tunnel -> ipsec -> to-hub -> tunnel-interface",
+		},
+	}
+	locations := []struct {
+		loc    Location
+		prefix string
+		device string
+	}{
+		{NGFWLocation(), "", ngfw},
+		{TemplateLocation("branch"), "template -> branch -> config -> devices -> localhost.localdomain -> ",
+			"/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='branch']/config/devices/entry[@name='localhost.localdomain']"},
+		{TemplateStackLocation("branch-stack"), "template-stack -> branch-stack -> config -> devices -> localhost.localdomain -> ",
+			"/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name='branch-stack']/config/devices/entry[@name='localhost.localdomain']"},
+	}
+	for _, l := range locations {
+		for _, tc := range cases {
+			t.Run(l.loc.String()+"/"+tc.name, func(t *testing.T) {
+				refs := parseInterfaceReferences(referenceLines(t, referenceError(l.prefix+tc.path)))
+				if len(refs) != 1 {
+					t.Fatalf("parseInterfaceReferences() = %v, want one reference", refs)
+				}
+				ref := refs[0]
+				if ref.Type != tc.refType || ref.Location != tc.location || ref.Name != tc.refName {
+					t.Errorf("reference = (%q, %q, %q), want (%q, %q, %q)", ref.Type, ref.Location, ref.Name, tc.refType, tc.location, tc.refName)
+				}
+				op, err := ref.detach("sdwan.901", l.loc)
+				if tc.xpath == "" {
+					if err == nil {
+						t.Errorf("detach() = %v, want an error", op)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("detach() error = %v", err)
+				}
+				if op.Action != "delete" || op.XPath != l.device+tc.xpath {
+					t.Errorf("detach() = %s %s, want delete %s", op.Action, op.XPath, l.device+tc.xpath)
+				}
+			})
+		}
+	}
+}
+
+func TestParseInterfaceReferencesAll(t *testing.T) {
+	body := referenceError(
+		"vsys -> vsys1 -> import -> network -> interface",
+		"vsys -> vsys1 -> zone -> untrust -> network -> layer3",
+		"vsys -> vsys1 -> zone -> untrust -> network -> layer3",
+		"network -> virtual-router -> default -> interface",
+		"network -> virtual-router -> default -> routing-table -> ip -> static-route -> to-hub -> interface",
+		"network -> virtual-router -> default -> routing-table -> ipv6 -> static-route -> to-hub -> interface",
+	)
+	refs := parseInterfaceReferences(referenceLines(t, body))
+	var got []string
+	for _, ref := range refs {
+		got = append(got, ref.String())
+	}
+	want := []string{
+		"vsys import vsys1",
+		"zone untrust in vsys1",
+		"virtual router default",
+		"static route to-hub in default ip",
+		"static route to-hub in default ipv6",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("parseInterfaceReferences() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestInterfaceReferenceOwnedBy(t *testing.T) {
+	refs := parseInterfaceReferences([]string{
+		" vsys -> vsys1 -> import -> network -> interface",
+		" vsys -> vsys1 -> zone -> untrust -> network -> layer3",
+		" vsys -> vsys2 -> zone -> untrust -> network -> layer3",
+		" network -> virtual-router -> default -> interface",
+		" network -> virtual-router -> other -> interface",
+	})
+	want := []bool{true, true, false, true, false}
+	for i, ref := range refs {
+		if got := ref.ownedBy("vsys1", "default", "untrust"); got != want[i] {
+			t.Errorf("%s ownedBy() = %v, want %v", ref, got, want[i])
+		}
+	}
+}
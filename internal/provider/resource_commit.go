@@ -0,0 +1,204 @@
+package pansdwan
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"time"
+
+	fwdiag "github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// XML Command Structs
+type commitCmd struct {
+	XMLName     xml.Name `xml:"commit"`
+	Description string   `xml:"description,omitempty"`
+	Admins      []string `xml:"partial>admin>member,omitempty"`
+}
+
+type pushTemplateStackCmd struct {
+	XMLName     xml.Name `xml:"commit-all"`
+	Name        string   `xml:"template-stack>name"`
+	Description string   `xml:"template-stack>description,omitempty"`
+}
+
+type pushDeviceGroupCmd struct {
+	XMLName     xml.Name `xml:"commit-all"`
+	Description string   `xml:"shared-policy>description,omitempty"`
+	DeviceGroup struct {
+		Name string `xml:"name,attr"`
+	} `xml:"shared-policy>device-group>entry"`
+}
+
+func resourceCommit() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Commits the changes made by the provider's admin on Panorama and pushes them to a template stack or device group. A new commit runs whenever `triggers` change.",
+		CreateContext: resourceCommitCreate,
+		ReadContext:   resourceCommitRead,
+		DeleteContext: resourceCommitDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+		},
+		// Schema for the resource
+		Schema: map[string]*schema.Schema{
+			"description": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Description recorded with the commit and push.",
+			},
+			"admins": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Admins whose changes are committed. Defaults to the provider username.",
+			},
+			"template_stack": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Template stack to push to after the commit.",
+			},
+			"device_group": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Device group to push to after the commit.",
+			},
+			"triggers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				ForceNew:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Arbitrary values that cause a new commit when changed, such as the IDs of the resources it depends on.",
+			},
+			"commit_job_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"push_job_ids": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func resourceCommitCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*APIClient)
+	description := d.Get("description").(string)
+
+	// Scope the commit to the provider's admin unless told otherwise
+	var admins []string
+	for _, v := range d.Get("admins").([]interface{}) {
+		admins = append(admins, v.(string))
+	}
+	if len(admins) == 0 {
+		if client.Username == "" {
+			return diag.Errorf("admins must be set when the provider authenticates with an API key")
+		}
+		admins = []string{client.Username}
+	}
+
+	// Commit to Panorama
+	cmd, err := xml.Marshal(commitCmd{Description: description, Admins: admins})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	commitJob, diags := submitCommit(ctx, client, "", string(cmd), "commit", d.Timeout(schema.TimeoutCreate))
+	if diags.HasError() {
+		return diags
+	}
+
+	// Push to the template stack and device group
+	var pushJobs []string
+	if stack := d.Get("template_stack").(string); stack != "" {
+		cmd, err := xml.Marshal(pushTemplateStackCmd{Name: stack, Description: description})
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		job, pushDiags := submitCommit(ctx, client, "all", string(cmd), fmt.Sprintf("push to template stack %s", stack), d.Timeout(schema.TimeoutCreate))
+		diags = append(diags, pushDiags...)
+		if diags.HasError() {
+			return diags
+		}
+		if job != "" {
+			pushJobs = append(pushJobs, job)
+		}
+	}
+	if dg := d.Get("device_group").(string); dg != "" {
+		var push pushDeviceGroupCmd
+		push.Description = description
+		push.DeviceGroup.Name = dg
+		cmd, err := xml.Marshal(push)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		job, pushDiags := submitCommit(ctx, client, "all", string(cmd), fmt.Sprintf("push to device group %s", dg), d.Timeout(schema.TimeoutCreate))
+		diags = append(diags, pushDiags...)
+		if diags.HasError() {
+			return diags
+		}
+		if job != "" {
+			pushJobs = append(pushJobs, job)
+		}
+	}
+
+	// Set the ID back to terraform as the commit job, or the time when there was nothing to commit
+	if commitJob != "" {
+		d.SetId(commitJob)
+	} else {
+		d.SetId(strconv.FormatInt(time.Now().Unix(), 10))
+	}
+	d.Set("admins", admins)
+	d.Set("commit_job_id", commitJob)
+	d.Set("push_job_ids", pushJobs)
+	return diags
+}
+
+func resourceCommitRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	// A commit is a one off action, there is nothing on the device to refresh
+	return nil
+}
+
+func resourceCommitDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	// Committed configuration cannot be uncommitted, just forget about it
+	d.SetId("")
+	return nil
+}
+
+// submitCommit sends a commit or push and waits for its job to finish. The job
+// ID is empty when PAN-OS had nothing to commit.
+func submitCommit(ctx context.Context, client *APIClient, action, cmd, what string, timeout time.Duration) (string, diag.Diagnostics) {
+	job, err := client.CommitJob(ctx, action, cmd, JobWaitOptions{Timeout: timeout})
+	if err == nil && job == nil {
+		tflog.Info(ctx, "Nothing to commit", map[string]interface{}{"operation": what})
+		return "", nil
+	}
+	var id string
+	if job != nil {
+		id = job.ID
+	}
+	return id, sdkDiagnostics(jobDiagnostics(what, job, err))
+}
+
+// sdkDiagnostics converts diagnostics from the shared helpers, which are
+// built for the framework resources, for this SDK resource.
+func sdkDiagnostics(diags fwdiag.Diagnostics) diag.Diagnostics {
+	var converted diag.Diagnostics
+	for _, d := range diags {
+		severity := diag.Error
+		if d.Severity() == fwdiag.SeverityWarning {
+			severity = diag.Warning
+		}
+		converted = append(converted, diag.Diagnostic{Severity: severity, Summary: d.Summary(), Detail: d.Detail()})
+	}
+	return converted
+}
@@ -2,539 +2,577 @@ package pansdwan
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"strings"
-	"time"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Modes of the on_destroy_references attribute
+const (
+	onDestroyReferencesFail   = "fail"
+	onDestroyReferencesDetach = "detach"
 )
 
 // XML Response Structs
-type KeyGenResponse struct {
+type sdwanInterface struct {
 	XMLName xml.Name `xml:"response"`
-	Text    string   `xml:",chardata"`
 	Status  string   `xml:"status,attr"`
+	Code    string   `xml:"code,attr"`
 	Result  struct {
-		Text string `xml:",chardata"`
-		Key  string `xml:"key"`
+		TotalCount string              `xml:"total-count,attr"`
+		Count      string              `xml:"count,attr"`
+		Entry      sdwanInterfaceEntry `xml:"entry"`
 	} `xml:"result"`
 }
 
-type sdwanInterface struct {
+type vsysImports struct {
 	XMLName xml.Name `xml:"response"`
-	Text    string   `xml:",chardata"`
-	Status  string   `xml:"status,attr"`
-	Code    string   `xml:"code,attr"`
 	Result  struct {
-		Text       string `xml:",chardata"`
-		TotalCount string `xml:"total-count,attr"`
-		Count      string `xml:"count,attr"`
-		Entry      struct {
-			Text      string `xml:",chardata"`
-			Name      string `xml:"name,attr"`
-			Protocol  string `xml:"protocol"`
-			Comment   string `xml:"comment"`
-			Interface struct {
-				Text   string   `xml:",chardata"`
-				Member []string `xml:"member"`
-			} `xml:"interface"`
+		Vsys []struct {
+			Name string `xml:"name,attr"`
 		} `xml:"entry"`
 	} `xml:"result"`
 }
 
-// Generate API key for PAN device
-func getAPIKey(deviceIP, username, password string, skip_verify bool) (string, error) {
-	// Create a custom HTTP client to allow insecure SSL connections if required by the provider
-	client := buildHttpClient(skip_verify)
-
-	// Construct the URL for the KeyGen API
-	keyGenURL := fmt.Sprintf("https://%s/api/?type=keygen&user=%s&password=%s", deviceIP, username, password)
+var (
+	_ resource.ResourceWithConfigure      = &sdwanInterfaceResource{}
+	_ resource.ResourceWithImportState    = &sdwanInterfaceResource{}
+	_ resource.ResourceWithUpgradeState   = &sdwanInterfaceResource{}
+	_ resource.ResourceWithValidateConfig = &sdwanInterfaceResource{}
+)
 
-	// Send the request to the PAN Device
-	resp, err := client.Get(keyGenURL)
-	if err != nil {
-		return "", fmt.Errorf("error making the request: %v", err)
-	}
-	defer resp.Body.Close()
+type sdwanInterfaceResource struct {
+	client *APIClient
+}
 
-	// Read the response body back from PAN
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("error reading the response body: %v", err)
-	}
+type sdwanInterfaceModel struct {
+	ID                  types.String `tfsdk:"id"`
+	Template            types.String `tfsdk:"template"`
+	Location            types.List   `tfsdk:"location"`
+	Name                types.String `tfsdk:"name"`
+	Members             types.List   `tfsdk:"members"`
+	Protocol            types.String `tfsdk:"protocol"`
+	Comment             types.String `tfsdk:"comment"`
+	Vsys                types.String `tfsdk:"vsys"`
+	VirtualRouter       types.String `tfsdk:"virtual_router"`
+	Zone                types.String `tfsdk:"zone"`
+	OnDestroyReferences types.String `tfsdk:"on_destroy_references"`
+}
 
-	// Parse the XML response to get the API Key
-	var keyGenResp KeyGenResponse
-	err = xml.Unmarshal(body, &keyGenResp)
-	if err != nil {
-		return "", fmt.Errorf("error unmarshalling the XML response: %v", err)
-	}
+func newSDWANInterfaceResource() resource.Resource {
+	return &sdwanInterfaceResource{}
+}
 
-	// Check if the API key was retrieved
-	if keyGenResp.Result.Key == "" {
-		return "", fmt.Errorf("API key not found in the response")
-	}
-	// Return the API key
-	return keyGenResp.Result.Key, nil
+func (r *sdwanInterfaceResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_sdwan_interface"
 }
 
-func resourceSDWANInterface() *schema.Resource {
-	return &schema.Resource{
-		CreateContext: resourceSDWANInterfaceCreate,
-		ReadContext:   resourceSDWANInterfaceRead,
-		UpdateContext: resourceSDWANInterfaceUpdate,
-		DeleteContext: resourceSDWANInterfaceDelete,
-		// Schema for the resource
-		Schema: map[string]*schema.Schema{
-			"template": {
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+func (r *sdwanInterfaceResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	// Schema for the resource, the same as when it was an SDK resource so existing state is read as is
+	resp.Schema = schema.Schema{
+		Version: 1,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 			},
-			"name": {
-				Type:     schema.TypeString,
+			"template": templateAttribute(),
+			"name": schema.StringAttribute{
 				Required: true,
-				ForceNew: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
-			"members": {
-				Type:     schema.TypeList,
-				Required: true,
-				Elem:     &schema.Schema{Type: schema.TypeString},
-				ForceNew: true,
+			"members": schema.ListAttribute{
+				Required:    true,
+				ElementType: types.StringType,
 			},
-			"protocol": {
-				Type:     schema.TypeString,
+			"protocol": schema.StringAttribute{
 				Optional: true,
-				Default:  "ipv4",
+				Computed: true,
+				Default:  stringdefault.StaticString("ipv4"),
 			},
-			"comment": {
-				Type:     schema.TypeString,
+			"comment": schema.StringAttribute{
 				Optional: true,
 			},
-			"vsys": {
-				Type:     schema.TypeString,
+			"vsys": schema.StringAttribute{
 				Required: true,
 			},
+			// The virtual router and zone are only managed when set, so an
+			// interface attached elsewhere (e.g. by a zone entry) is left alone
+			"virtual_router": schema.StringAttribute{
+				Optional: true,
+			},
+			"zone": schema.StringAttribute{
+				Optional: true,
+			},
+			// What to do on destroy when other config still references the
+			// interface, beyond the vsys, virtual router and zone set here
+			"on_destroy_references": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(onDestroyReferencesFail),
+				Validators: []validator.String{
+					stringvalidator.OneOf(onDestroyReferencesFail, onDestroyReferencesDetach),
+				},
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"location": locationBlock(),
 		},
 	}
 }
 
-func addInterfaceToVsys(apiKey, deviceIP, username, password, interfaceToAdd, template, vsys string, skip_verify bool) diag.Diagnostics {
-	// Create a custom HTTP client to allow insecure SSL connections if required by the provider
-	client := buildHttpClient(skip_verify)
-
-	// Construct the URL to import interface into vsys
-	vsysURL := fmt.Sprintf("https://%s/api/?type=config&action=set&xpath=/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='%s']/import/network/interface&element=<member>%s</member>", deviceIP, url.QueryEscape(template), url.QueryEscape(vsys), url.QueryEscape(interfaceToAdd))
-
-	req, _ := http.NewRequest("GET", vsysURL, nil)
-	req.Header.Set("Content-Type", "application/xml")
-	req.Header.Set("X-PAN-KEY", apiKey)
+func (r *sdwanInterfaceResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = configureClient(req.ProviderData, &resp.Diagnostics)
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return diag.FromErr(err)
-	}
-	defer resp.Body.Close()
-	// Read and check response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return diag.FromErr(err)
+func (r *sdwanInterfaceResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config sdwanInterfaceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	if resp.StatusCode != 200 {
-		return diag.Errorf("Failed to add %s to vsys: %s", interfaceToAdd, string(body))
-	}
-	// Catch failures in the response
-	resp_err := checkXMLResponse(body)
-	if resp_err != nil {
-		return diag.FromErr(resp_err)
-	}
-	// Return nothing as we only return the error if there was one
-	return nil
+	resp.Diagnostics.Append(validateLocationConfig(ctx, config.Template, config.Location)...)
 }
 
-func removeInterfaceFromVsys(apiKey, deviceIP, username, password, interfaceToRemove, template, vsys string, skip_verify bool) diag.Diagnostics {
-	// Create a custom HTTP client to allow insecure SSL connections if required by the provider
-	client := buildHttpClient(skip_verify)
+// addInterfaceToVsys returns the change importing an interface into a vsys.
+func addInterfaceToVsys(interfaceToAdd string, loc Location, vsys string) ConfigOp {
+	return SetOp(loc.VsysImportInterfaceXPath(vsys), memberElement(interfaceToAdd))
+}
 
-	// Construct the URL to remove interface from vsys
-	vsysURL := fmt.Sprintf("https://%s/api/?type=config&action=delete&xpath=/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='%s']/import/network/interface/member[text()='%s']", deviceIP, url.QueryEscape(template), url.QueryEscape(vsys), url.QueryEscape(interfaceToRemove))
-	req, _ := http.NewRequest("GET", vsysURL, nil)
-	req.Header.Set("Content-Type", "application/xml")
-	req.Header.Set("X-PAN-KEY", apiKey)
+// removeInterfaceFromVsys returns the change removing an interface from a vsys.
+func removeInterfaceFromVsys(interfaceToRemove string, loc Location, vsys string) ConfigOp {
+	return DeleteOp(memberXPath(loc.VsysImportInterfaceXPath(vsys), interfaceToRemove))
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return diag.FromErr(err)
-	}
-	defer resp.Body.Close()
-	// Read and check response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return diag.FromErr(err)
-	}
-	if resp.StatusCode != 200 {
-		return diag.Errorf("Failed to remove %s from vsys: %s", interfaceToRemove, string(body))
-	}
-	// Catch failures in the response
-	resp_err := checkXMLResponse(body)
-	if resp_err != nil {
-		return diag.FromErr(resp_err)
-	}
-	// Return nothing as we only return the error if there was one
-	return nil
+// addInterfaceToVr returns the change adding an interface to a virtual router.
+func addInterfaceToVr(interfaceToAdd string, loc Location, vr string) ConfigOp {
+	return SetOp(loc.VirtualRouterInterfaceXPath(vr), memberElement(interfaceToAdd))
 }
 
-func removeInterfaceFromVr(apiKey, deviceIP, username, password, interfaceToRemove, template, vr string, skip_verify bool) diag.Diagnostics {
-	// Create a custom HTTP client to allow insecure SSL connections if required by the provider
-	client := buildHttpClient(skip_verify)
+// removeInterfaceFromVr returns the change removing an interface from a virtual router.
+func removeInterfaceFromVr(interfaceToRemove string, loc Location, vr string) ConfigOp {
+	return DeleteOp(memberXPath(loc.VirtualRouterInterfaceXPath(vr), interfaceToRemove))
+}
 
-	// Construct the URL to remove interface from virtual router
-	vsysURL := fmt.Sprintf("https://%s/api/?type=config&action=delete&xpath=/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']/config/devices/entry[@name='localhost.localdomain']/network/virtual-router/entry[@name='%s']/interface/member[text()='%s']", deviceIP, url.QueryEscape(template), url.QueryEscape(vr), url.QueryEscape(interfaceToRemove))
-	req, _ := http.NewRequest("GET", vsysURL, nil)
-	req.Header.Set("Content-Type", "application/xml")
-	req.Header.Set("X-PAN-KEY", apiKey)
+// addInterfaceToZone returns the change adding an interface to a zone.
+func addInterfaceToZone(interfaceToAdd string, loc Location, vsys, zone string) ConfigOp {
+	return SetOp(loc.ZoneLayer3XPath(vsys, zone), memberElement(interfaceToAdd))
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return diag.FromErr(err)
-	}
-	defer resp.Body.Close()
-	// Read and check response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return diag.FromErr(err)
-	}
-	if resp.StatusCode != 200 {
-		return diag.Errorf("Failed to remove %s from virtual-router: %s", interfaceToRemove, string(body))
-	}
-	// Catch failures in the response
-	resp_err := checkXMLResponse(body)
-	if resp_err != nil {
-		return diag.FromErr(resp_err)
-	}
-	// Return nothing as we only return the error if there was one
-	return nil
+// removeInterfaceFromZone returns the change removing an interface from a zone.
+func removeInterfaceFromZone(interfaceToRemove string, loc Location, vsys, zone string) ConfigOp {
+	return DeleteOp(memberXPath(loc.ZoneLayer3XPath(vsys, zone), interfaceToRemove))
 }
 
-func removeInterfaceFromZone(apiKey, deviceIP, username, password, interfaceToRemove, template, vsys, zone string, skip_verify bool) diag.Diagnostics {
-	// Create a custom HTTP client to allow insecure SSL connections if required by the provider
-	client := buildHttpClient(skip_verify)
+// buildSdwanInterfaceElement returns the complete XML entry for the sdwan
+// interface. Create and Update both use it so they write identical config.
+func buildSdwanInterfaceElement(name, protocol, comment string, interfaces []string) (string, error) {
+	entry := sdwanInterfaceEntry{
+		Name:     name,
+		Protocol: protocol,
+		Comment:  comment,
+	}
+	entry.Members = append(entry.Members, interfaces...)
+	return marshalElement(entry)
+}
 
-	// Construct the URL to remove interface from zone
-	vsysURL := fmt.Sprintf("https://%s/api/?type=config&action=delete&xpath=/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='%s']/zone/entry[@name='%s']/network/layer3/member[text()='%s']", deviceIP, url.QueryEscape(template), url.QueryEscape(vsys), url.QueryEscape(zone), url.QueryEscape(interfaceToRemove))
-	req, _ := http.NewRequest("GET", vsysURL, nil)
-	req.Header.Set("Content-Type", "application/xml")
-	req.Header.Set("X-PAN-KEY", apiKey)
+func (r *sdwanInterfaceResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !clientConfigured(r.client, &resp.Diagnostics) {
+		return
+	}
+	var plan sdwanInterfaceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	var members []string
+	resp.Diagnostics.Append(plan.Members.ElementsAs(ctx, &members, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	name := plan.Name.ValueString()
 
-	resp, err := client.Do(req)
+	// Create XML Element string from resource inputs
+	elementString, err := buildSdwanInterfaceElement(name, plan.Protocol.ValueString(), plan.Comment.ValueString(), members)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Failed to build SD-WAN interface", err.Error())
+		return
 	}
-	defer resp.Body.Close()
-	// Read and check response body
-	body, err := io.ReadAll(resp.Body)
+	loc, err := locationFromModel(ctx, plan.Template, plan.Location)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Invalid location", err.Error())
+		return
 	}
-	if resp.StatusCode != 200 {
-		return diag.Errorf("Failed to remove %s from zone %s: %s", interfaceToRemove, zone, string(body))
+	// Construct the xpath of the sdwan interface units to create the interface in
+	xpath := loc.SdwanInterfaceUnitsXPath()
+
+	// Create the interface and add it to the required vsys, virtual router and zone in one atomic change
+	ops := []ConfigOp{
+		SetOp(xpath, elementString),
+		addInterfaceToVsys(name, loc, plan.Vsys.ValueString()),
 	}
-	// Catch failures in the response
-	resp_err := checkXMLResponse(body)
-	if resp_err != nil {
-		return diag.FromErr(resp_err)
+	if vr := plan.VirtualRouter.ValueString(); vr != "" {
+		ops = append(ops, addInterfaceToVr(name, loc, vr))
 	}
-	// Return nothing as we only return the error if there was one
-	return nil
-}
-
-func buildSdwanInterfaceElement(protocol, comment string, interfaces []interface{}) string {
-	// Build the XML element string for the sdwan interface
-	var sb strings.Builder
-	members := make([]string, len(interfaces))
-	for i, v := range interfaces {
-		members[i] = v.(string)
+	if zone := plan.Zone.ValueString(); zone != "" {
+		ops = append(ops, addInterfaceToZone(name, loc, plan.Vsys.ValueString(), zone))
 	}
-	sb.WriteString(fmt.Sprintf("<protocol>%s</protocol>", protocol))
-	sb.WriteString(fmt.Sprintf("<comment>%s</comment>", strings.ReplaceAll(comment, " ", "%20")))
-	sb.WriteString("<interface>")
-
-	for _, intf := range members {
-		sb.WriteString(fmt.Sprintf("<member>%s</member>", intf))
+	resp.Diagnostics.Append(applyConfigOps(ctx, r.client, fmt.Sprintf("Failed to create SD-WAN interface with URL %s", r.client.Endpoint()), ops...)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	sb.WriteString("</interface>")
-	return sb.String()
+	// Set the ID back to terraform as the location and name of the interface
+	plan.ID = types.StringValue(sdwanInterfaceID(loc, name))
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
 }
 
-func resourceSDWANInterfaceCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*APIClient)
-
-	// Create XML Element string from resourc inputs
-	elementString := buildSdwanInterfaceElement(d.Get("protocol").(string), d.Get("comment").(string), d.Get("members").([]interface{}))
-	// Construct the URL to create the sdwan interface
-	req_url := fmt.Sprintf("https://%s/api/?type=config&action=set&xpath=/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']/config/devices/entry[@name='localhost.localdomain']/network/interface/sdwan/units/entry[@name='%s']&element=%s",
-		client.Host, url.QueryEscape(d.Get("template").(string)), url.QueryEscape(d.Get("name").(string)), elementString)
-
-	req, _ := http.NewRequest("GET", req_url, nil)
-	apiKey, _ := getAPIKey(client.Host, client.Username, client.Password, client.SkipSSLVerification)
-	req.Header.Set("Content-Type", "application/xml")
-	req.Header.Set("X-PAN-KEY", apiKey)
-	httpClient := buildHttpClient(client.SkipSSLVerification)
-
-	resp, err := httpClient.Do(req)
+func (r *sdwanInterfaceResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if !clientConfigured(r.client, &resp.Diagnostics) {
+		return
+	}
+	var state sdwanInterfaceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	loc, err := locationFromModel(ctx, state.Template, state.Location)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Invalid location", err.Error())
+		return
+	}
+	name := state.Name.ValueString()
+	// Construct the xpath to get the sdwan interface
+	xpath := loc.SdwanInterfaceXPath(name)
+
+	body, err := r.client.Get(ctx, xpath)
+	if errors.Is(err, ErrObjectNotPresent) {
+		// This means the interface does not exist so remove it from the state
+		resp.State.RemoveResource(ctx)
+		resp.Diagnostics.Append(stackObjectNotPresent(ctx, r.client, loc, fmt.Sprintf("SD-WAN interface %s", name), func(l Location) string {
+			return l.SdwanInterfaceXPath(name)
+		})...)
+		return
 	}
-	defer resp.Body.Close()
-	// Read and check response body
-	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.Append(diagFromErr("Error getting sdwan interface", err)...)
+		return
 	}
-	if resp.StatusCode != 200 {
-		return diag.Errorf("Failed to create SD-WAN interface with the following element: %s. Error: %s. With URL: %s", elementString, string(body), req_url)
+	var sdwan_xml_resp sdwanInterface
+	if err := decodeXML(body, &sdwan_xml_resp); err != nil {
+		resp.Diagnostics.Append(diagFromErr("Error parsing sdwan interface", err)...)
+		return
 	}
-	// Catch failures in the response
-	resp_err := checkXMLResponse(body)
-	if resp_err != nil {
-		return diag.FromErr(resp_err)
+	// Set the resource data back to terraform
+	entry := sdwan_xml_resp.Result.Entry
+	members, diags := types.ListValueFrom(ctx, types.StringType, append([]string{}, entry.Members...))
+	resp.Diagnostics.Append(diags...)
+	state.Template, state.Location = normalizeLocation(state.Template, state.Location, loc)
+	state.Name = types.StringValue(entry.Name)
+	state.Members = members
+	state.Protocol = types.StringValue(entry.Protocol)
+	state.Comment = optionalString(state.Comment, entry.Comment)
+	if state.OnDestroyReferences.IsNull() {
+		state.OnDestroyReferences = types.StringValue(onDestroyReferencesFail)
+	}
+	// Set the vsys from the device so a changed or removed import shows as drift
+	vsys, err := findInterfaceVsys(ctx, r.client, loc, name, state.Vsys.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(diagFromErr("Error getting sdwan interface vsys", err)...)
+		return
+	}
+	state.Vsys = types.StringValue(vsys)
+	// Set the virtual router and zone from the device when they are managed here
+	if state.VirtualRouter.ValueString() != "" {
+		vr, err := findInterfaceVr(ctx, r.client, loc, name)
+		if err != nil {
+			resp.Diagnostics.Append(diagFromErr("Error getting sdwan interface virtual router", err)...)
+			return
+		}
+		state.VirtualRouter = optionalString(state.VirtualRouter, vr)
 	}
-	// Add the sdwan interface to the required vsys as per the resource input
-	vsys_add_err := addInterfaceToVsys(apiKey, client.Host, client.Username, client.Password, d.Get("name").(string), d.Get("template").(string), d.Get("vsys").(string), client.SkipSSLVerification)
-	if vsys_add_err != nil {
-		return diag.Errorf("addInterfaceToVsys error: %s, %s", vsys_add_err[0].Summary, vsys_add_err[0].Detail)
+	if state.Zone.ValueString() != "" {
+		_, zone, err := findInterfaceZone(ctx, r.client, loc, name)
+		if err != nil {
+			resp.Diagnostics.Append(diagFromErr("Error getting sdwan interface zone", err)...)
+			return
+		}
+		state.Zone = optionalString(state.Zone, zone)
 	}
-	// Set the ID back to terraform as the name of the interface
-	d.SetId(d.Get("name").(string))
-	// Return nothing as we only return the error if there was one
-	return nil
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
 }
 
-func resourceSDWANInterfaceRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*APIClient)
-	// Construct the URL to get the sdwan interface
-	req_url := fmt.Sprintf("https://%s/api/?type=config&action=get&xpath=/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']/config/devices/entry[@name='localhost.localdomain']/network/interface/sdwan/units/entry[@name='%s']",
-		client.Host, url.QueryEscape(d.Get("template").(string)), url.QueryEscape(d.Get("name").(string)))
-
-	req, _ := http.NewRequest("GET", req_url, nil)
-	apiKey, _ := getAPIKey(client.Host, client.Username, client.Password, client.SkipSSLVerification)
-	req.Header.Set("Content-Type", "application/xml")
-	req.Header.Set("X-PAN-KEY", apiKey)
-	httpClient := buildHttpClient(client.SkipSSLVerification)
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return diag.FromErr(err)
+// findInterfaceVsys returns the vsys that imports the interface, preferring
+// current if more than one does. It is empty when no vsys imports it.
+func findInterfaceVsys(ctx context.Context, client *APIClient, loc Location, iface, current string) (string, error) {
+	body, err := client.Get(ctx, loc.VsysByImportInterfaceXPath(iface))
+	if errors.Is(err, ErrObjectNotPresent) {
+		return "", nil
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	var sdwan_xml_resp sdwanInterface
-	if err := xml.Unmarshal(body, &sdwan_xml_resp); err != nil {
-		panic(err)
+	if err != nil {
+		return "", err
 	}
-	// Catch failures in the response
-	if resp.StatusCode != 200 {
-		return diag.Errorf("Error getting sdwan interface: %s", string(body))
+	var resp vsysImports
+	if err := decodeXML(body, &resp); err != nil {
+		return "", err
 	}
-	if sdwan_xml_resp.Status == "error" {
-		return diag.Errorf("Error getting sdwan interface: %s", string(body))
+	for _, vsys := range resp.Result.Vsys {
+		if vsys.Name == current {
+			return current, nil
+		}
 	}
-	// Check if the interface exists
-	if sdwan_xml_resp.Code == "7" {
-		// This means the interface does not exist set the ID to empty and return
-		d.SetId("")
-		return nil
+	if len(resp.Result.Vsys) > 0 {
+		return resp.Result.Vsys[0].Name, nil
 	}
-	// Set the resource data back to terraform
-	d.Set("template", d.Get("template").(string))
-	d.Set("name", sdwan_xml_resp.Result.Entry.Name)
-	d.Set("members", sdwan_xml_resp.Result.Entry.Interface.Member)
-	d.Set("protocol", sdwan_xml_resp.Result.Entry.Protocol)
-	d.Set("comment", sdwan_xml_resp.Result.Entry.Comment)
-	d.Set("vsys", d.Get("vsys").(string))
-	// Return nothing as we only return the error if there was one
-	return nil
+	return "", nil
 }
 
-func resourceSDWANInterfaceUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*APIClient)
+// findInterfaceVr returns the virtual router the interface is in, or an empty
+// string if it is in none.
+func findInterfaceVr(ctx context.Context, client *APIClient, loc Location, iface string) (string, error) {
+	return firstEntryName(ctx, client, loc.VirtualRouterByInterfaceXPath(iface))
+}
 
-	// Construct the URL to set the sdwan interface parameters
-	req_url := fmt.Sprintf("https://%s/api/?type=config&action=set&xpath=/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']/config/devices/entry[@name='localhost.localdomain']/network/interface/sdwan/units/entry[@name='%s']&element=<protocol>%s</protocol><comment>%s</comment>",
-		client.Host, url.QueryEscape(d.Get("template").(string)), url.QueryEscape(d.Get("name").(string)), url.QueryEscape(d.Get("protocol").(string)), url.QueryEscape(d.Get("comment").(string)))
+// ImportState accepts an ID of the form <location>:<vsys>:<name>, for example
+// `branch:vsys1:sdwan.901`, or the resource ID <location>:<name>. Read then
+// fills in everything else, including the vsys when it is not given. The
+// location is always imported as the location block, which a configuration
+// using template resolves to as well, so it plans no replacement.
+func (r *sdwanInterfaceResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	var loc Location
+	var vsys, name string
+	if strings.Count(req.ID, ":") == 1 {
+		l, parts, err := splitImportID(req.ID, "<location>:<name>", 1)
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid import ID", err.Error())
+			return
+		}
+		loc, name = l, parts[0]
+	} else {
+		l, parts, err := splitImportID(req.ID, "<location>:<vsys>:<name>", 2)
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid import ID", err.Error())
+			return
+		}
+		loc, vsys, name = l, parts[0], parts[1]
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("location"), locationValue(loc))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("vsys"), vsys)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), name)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("on_destroy_references"), onDestroyReferencesFail)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), sdwanInterfaceID(loc, name))...)
+}
 
-	req, _ := http.NewRequest("GET", req_url, nil)
-	apiKey, _ := getAPIKey(client.Host, client.Username, client.Password, client.SkipSSLVerification)
-	req.Header.Set("Content-Type", "application/xml")
-	req.Header.Set("X-PAN-KEY", apiKey)
-	httpClient := buildHttpClient(client.SkipSSLVerification)
+// UpgradeState upgrades state from before IDs were location qualified, when
+// the ID was just the interface name.
+func (r *sdwanInterfaceResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: {
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				upgradeRawState(ctx, r, req, resp, sdwanInterfaceStateUpgradeV0)
+			},
+		},
+	}
+}
 
-	resp, err := httpClient.Do(req)
+// sdwanInterfaceStateUpgradeV0 replaces the bare interface name ID, which
+// collides across templates, with the location qualified ID. The SDK stored
+// "" for unset optional arguments, which the framework stores as null.
+func sdwanInterfaceStateUpgradeV0(rawState map[string]interface{}) (map[string]interface{}, error) {
+	loc, err := locationFromRawState(rawState)
 	if err != nil {
-		return diag.FromErr(err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-	// Catch failures in the response
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return diag.Errorf("API error updating sdwan interface: %s", string(body))
-	} else {
-		// Check to see if the vsys has changed on the resource
-		// If it has changed we need to remove the interface from the old vsys and add it to the new one
-		if d.HasChange("vsys") {
-			vsys_before, vsys_after := d.GetChange("vsys")
-			fmt.Println("Detected vsys change on SD-WAN interface")
-			fmt.Println("vsys before:", vsys_before)
-			fmt.Println("vsys after:", vsys_after)
-			// Remove the interface from the old vsys
-			if vsys_before.(string) != "" {
-				sdwan_vsys_rm_err := removeInterfaceFromVsys(apiKey, client.Host, client.Username, client.Password, d.Get("name").(string), d.Get("template").(string), vsys_before.(string), client.SkipSSLVerification)
-				if sdwan_vsys_rm_err != nil {
-					return diag.Errorf("SDWAN Update, Vsys remove error: %s, %s", sdwan_vsys_rm_err[0].Summary, sdwan_vsys_rm_err[0].Detail)
-				}
-			}
-			// Add the interface to the new vsys
-			sdwan_vsys_add_err := addInterfaceToVsys(apiKey, client.Host, client.Username, client.Password, d.Get("name").(string), d.Get("template").(string), vsys_after.(string), client.SkipSSLVerification)
-			if sdwan_vsys_add_err != nil {
-				return diag.Errorf("SDWAN Update, Vsys add error: %s, %s", sdwan_vsys_add_err[0].Summary, sdwan_vsys_add_err[0].Detail)
-
-			}
+	name, _ := rawState["name"].(string)
+	rawState["id"] = sdwanInterfaceID(loc, name)
+	for _, k := range []string{"comment", "virtual_router", "zone"} {
+		if rawState[k] == "" {
+			rawState[k] = nil
 		}
 	}
-	// Return nothing as we only return the error if there was one
-	return nil
+	return rawState, nil
 }
 
-func resourceSDWANInterfaceDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*APIClient)
-
-	// Construct the URL to delete the sdwan interface - this is likely to fail if the interface is still referenced elsewhere
-	req_url := fmt.Sprintf("https://%s/api/?type=config&action=delete&xpath=/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']/config/devices/entry[@name='localhost.localdomain']/network/interface/sdwan/units/entry[@name='%s']",
-		client.Host, url.QueryEscape(d.Get("template").(string)), url.QueryEscape(d.Get("name").(string)))
-
-	req, _ := http.NewRequest("GET", req_url, nil)
-	apiKey, _ := getAPIKey(client.Host, client.Username, client.Password, client.SkipSSLVerification)
-	req.Header.Set("Content-Type", "application/xml")
-	req.Header.Set("X-PAN-KEY", apiKey)
-	httpClient := buildHttpClient(client.SkipSSLVerification)
+func (r *sdwanInterfaceResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !clientConfigured(r.client, &resp.Diagnostics) {
+		return
+	}
+	var plan, state sdwanInterfaceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	var members []string
+	resp.Diagnostics.Append(plan.Members.ElementsAs(ctx, &members, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	loc, err := locationFromModel(ctx, plan.Template, plan.Location)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid location", err.Error())
+		return
+	}
+	name := plan.Name.ValueString()
+	// Construct the xpath to replace the sdwan interface, so removed members and comments are removed on the device too
+	xpath := loc.SdwanInterfaceXPath(name)
+	element, err := buildSdwanInterfaceElement(name, plan.Protocol.ValueString(), plan.Comment.ValueString(), members)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to build SD-WAN interface", err.Error())
+		return
+	}
+
+	ops := []ConfigOp{EditOp(xpath, element)}
+	vsys_before, vsys_after := state.Vsys.ValueString(), plan.Vsys.ValueString()
+	zone_before, zone_after := state.Zone.ValueString(), plan.Zone.ValueString()
+	vr_before, vr_after := state.VirtualRouter.ValueString(), plan.VirtualRouter.ValueString()
+	// The zone is in the vsys, so it has to move when either of them changes
+	zone_changed := zone_before != zone_after || vsys_before != vsys_after
+	// Detach the interface from the old zone and virtual router before it leaves the old vsys
+	if zone_changed && zone_before != "" {
+		ops = append(ops, removeInterfaceFromZone(name, loc, vsys_before, zone_before))
+	}
+	if vr_before != vr_after {
+		tflog.Info(ctx, "Detected virtual router change on SD-WAN interface", map[string]interface{}{
+			"virtual_router_before": vr_before,
+			"virtual_router_after":  vr_after,
+		})
+		if vr_before != "" {
+			ops = append(ops, removeInterfaceFromVr(name, loc, vr_before))
+		}
+	}
+	// Check to see if the vsys has changed on the resource
+	// If it has changed we need to remove the interface from the old vsys and add it to the new one
+	if vsys_before != vsys_after {
+		tflog.Info(ctx, "Detected vsys change on SD-WAN interface", map[string]interface{}{
+			"vsys_before": vsys_before,
+			"vsys_after":  vsys_after,
+		})
+		// Remove the interface from the old vsys
+		if vsys_before != "" {
+			ops = append(ops, removeInterfaceFromVsys(name, loc, vsys_before))
+		}
+		// Add the interface to the new vsys
+		ops = append(ops, addInterfaceToVsys(name, loc, vsys_after))
+	}
+	// Attach the interface to the new virtual router and zone
+	if vr_before != vr_after && vr_after != "" {
+		ops = append(ops, addInterfaceToVr(name, loc, vr_after))
+	}
+	if zone_changed && zone_after != "" {
+		if zone_before != zone_after {
+			tflog.Info(ctx, "Detected zone change on SD-WAN interface", map[string]interface{}{
+				"zone_before": zone_before,
+				"zone_after":  zone_after,
+			})
+		}
+		ops = append(ops, addInterfaceToZone(name, loc, vsys_after, zone_after))
+	}
+	resp.Diagnostics.Append(applyConfigOps(ctx, r.client, "API error updating sdwan interface", ops...)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	plan.ID = types.StringValue(sdwanInterfaceID(loc, name))
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
 
-	resp, err := httpClient.Do(req)
+func (r *sdwanInterfaceResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !clientConfigured(r.client, &resp.Diagnostics) {
+		return
+	}
+	var state sdwanInterfaceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	loc, err := locationFromModel(ctx, state.Template, state.Location)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Invalid location", err.Error())
+		return
 	}
-	defer resp.Body.Close()
+	name := state.Name.ValueString()
+	// Construct the xpath to delete the sdwan interface - this is likely to fail if the interface is still referenced elsewhere
+	xpath := loc.SdwanInterfaceXPath(name)
 
-	// Read the response body
-	body, _ := io.ReadAll(resp.Body)
-	var xml_resp XMLAPIResponse
-	if err := xml.Unmarshal(body, &xml_resp); err != nil {
-		panic(err)
+	_, err = r.client.Delete(ctx, xpath)
+	if err == nil || errors.Is(err, ErrObjectNotPresent) {
+		// The interface has been deleted, the framework removes it from the state
+		return
 	}
 	// Catch failures in the response - which are expected if the interface is still referenced elsewhere
-	if resp.StatusCode != 200 {
-		return diag.Errorf("API error deleting sd-wan interface: %s", string(body))
-	}
-	dependency_err := false
-	if xml_resp.Status == "error" {
-		// Likely the interface is still referenced elsewhere
-		for _, line := range xml_resp.Msg.Lines {
-			if strings.Contains(line, "cannot be deleted because of references from") {
-				dependency_err = true
-				fmt.Println("Found dependency error:", line)
-			}
+	var apiErr *APIError
+	if !errors.Is(err, ErrReferenceCountNotZero) || !errors.As(err, &apiErr) {
+		resp.Diagnostics.Append(diagFromErr("API error deleting sd-wan interface", err)...)
+		return
+	}
+	tflog.Info(ctx, "Found dependency error", map[string]interface{}{"message": apiErr.Message()})
+	// The vsys, virtual router and zone set on the resource are detached as part of destroying it,
+	// anything else referencing the interface is only detached when asked to
+	var owned, foreign []interfaceReference
+	for _, ref := range parseInterfaceReferences(apiErr.Lines) {
+		if ref.ownedBy(state.Vsys.ValueString(), state.VirtualRouter.ValueString(), state.Zone.ValueString()) {
+			owned = append(owned, ref)
+		} else {
+			foreign = append(foreign, ref)
+		}
+	}
+	if len(foreign) > 0 && state.OnDestroyReferences.ValueString() != onDestroyReferencesDetach {
+		var refs []string
+		for _, ref := range foreign {
+			refs = append(refs, ref.String())
+		}
+		resp.Diagnostics.AddError(fmt.Sprintf("SD-WAN interface %s is still referenced", name),
+			fmt.Sprintf("SD-WAN interface %s in %s cannot be deleted because it is still referenced by:\n  %s\n\nRemove these references, or set on_destroy_references = \"detach\" to remove the interface from them on destroy.",
+				name, loc, strings.Join(refs, "\n  ")))
+		return
+	}
+	// Remove the interface from everything else before removing it from its vsys
+	var ops, vsysOps []ConfigOp
+	var manual []string
+	for _, ref := range append(owned, foreign...) {
+		op, err := ref.detach(name, loc)
+		if err != nil {
+			manual = append(manual, err.Error())
+			continue
 		}
-		if dependency_err {
-			// Parse the err to find the dependencies
-			var virtualRouter, vsys, zone string
-			for _, line := range xml_resp.Msg.Lines {
-				line = strings.TrimSpace(line)
-				if strings.Contains(line, "virtual-router") {
-					parts := strings.Split(line, "->")
-					for i, part := range parts {
-						if strings.TrimSpace(part) == "virtual-router" && i+1 < len(parts) {
-							virtualRouter = strings.TrimSpace(parts[i+1])
-						}
-					}
-				}
-				if strings.Contains(line, "vsys") {
-					parts := strings.Split(line, "->")
-					for i, part := range parts {
-						if strings.TrimSpace(part) == "vsys" && i+1 < len(parts) {
-							vsys = strings.TrimSpace(parts[i+1])
-						}
-						if strings.TrimSpace(part) == "zone" && i+1 < len(parts) {
-							zone = strings.TrimSpace(parts[i+1])
-						}
-					}
-				}
-			}
-			// Remove the interface from its Virtual Router if its associated
-			if virtualRouter != "" {
-				vr_err := removeInterfaceFromVr(apiKey, client.Host, client.Username, client.Password, d.Get("name").(string), d.Get("template").(string), virtualRouter, client.SkipSSLVerification)
-				if vr_err != nil {
-					return diag.Errorf("SDWAN Delete, VR remove error: %s, %s", vr_err[0].Summary, vr_err[0].Detail)
-				}
-			}
-			// Remove the interface from its Zone if its associated
-			if zone != "" {
-				zone_err := removeInterfaceFromZone(apiKey, client.Host, client.Username, client.Password, d.Get("name").(string), d.Get("template").(string), vsys, zone, client.SkipSSLVerification)
-				if zone_err != nil {
-					return diag.Errorf("SDWAN Delete, zone remove error: %s, %s", zone_err[0].Summary, zone_err[0].Detail)
-
-				}
-			}
-			// Remove the interface from its Vsys if its associated
-
-			if vsys != "" {
-				vsys_err := removeInterfaceFromVsys(apiKey, client.Host, client.Username, client.Password, d.Get("name").(string), d.Get("template").(string), vsys, client.SkipSSLVerification)
-				if vsys_err != nil {
-					return diag.Errorf("SDWAN Delete, vsys remove error: %s, %s", vsys_err[0].Summary, vsys_err[0].Detail)
-				}
-			}
-			// Construct the URL to delete the sdwan interface - now dependencies should be removed and this should work
-			req_url := fmt.Sprintf("https://%s/api/?type=config&action=delete&xpath=/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']/config/devices/entry[@name='localhost.localdomain']/network/interface/sdwan/units/entry[@name='%s']",
-				client.Host, url.QueryEscape(d.Get("template").(string)), url.QueryEscape(d.Get("name").(string)))
-
-			req, _ := http.NewRequest("GET", req_url, nil)
-			req.Header.Set("Content-Type", "application/xml")
-			req.Header.Set("X-PAN-KEY", apiKey)
-			tr := &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: client.SkipSSLVerification},
-			}
-			clientinsecure := &http.Client{
-				Transport: tr,
-				Timeout:   30 * time.Second, // Set a timeout for the request
-			}
-
-			resp, err := clientinsecure.Do(req)
-			if err != nil {
-				return diag.FromErr(err)
-			}
-			defer resp.Body.Close()
-			// Read and check response body
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return diag.FromErr(err)
-			}
-			if resp.StatusCode != 200 {
-				return diag.Errorf("Failed to remove interface from virtual-router: %s", string(body))
-			}
-			// Catch failures in the response
-			resp_err := checkXMLResponse(body)
-			if resp_err != nil {
-				return diag.FromErr(resp_err)
-			}
+		if ref.Type == refVsysImport {
+			vsysOps = append(vsysOps, op)
+		} else {
+			ops = append(ops, op)
 		}
 	}
-	// Set the ID back to empty as the interface has been deleted
-	d.SetId("")
-	// Return nothing as we only return the error if there was one
-	return nil
+	if len(manual) > 0 {
+		resp.Diagnostics.AddError(fmt.Sprintf("SD-WAN interface %s is still referenced", name),
+			fmt.Sprintf("SD-WAN interface %s in %s cannot be deleted, these references have to be removed by hand:\n  %s",
+				name, loc, strings.Join(manual, "\n  ")))
+		return
+	}
+	ops = append(ops, vsysOps...)
+	// Delete the sdwan interface along with its dependencies so a failure leaves the references in place
+	ops = append(ops, DeleteOp(xpath))
+	resp.Diagnostics.Append(applyConfigOps(ctx, r.client, "Failed to delete sd-wan interface", ops...)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// Show every detachment in the apply output, as none of them are in the plan
+	for _, ref := range owned {
+		tflog.Info(ctx, "Detached SD-WAN interface from its own reference", map[string]interface{}{"interface": name, "reference": ref.String()})
+		resp.Diagnostics.AddWarning(fmt.Sprintf("Detached SD-WAN interface %s from %s", name, ref),
+			fmt.Sprintf("SD-WAN interface %s in %s was removed from %s, which is set on the resource, before it was deleted.", name, loc, ref))
+	}
+	for _, ref := range foreign {
+		tflog.Warn(ctx, "Detached SD-WAN interface from a reference", map[string]interface{}{"interface": name, "reference": ref.String()})
+		resp.Diagnostics.AddWarning(fmt.Sprintf("Detached SD-WAN interface %s from %s", name, ref),
+			fmt.Sprintf("SD-WAN interface %s in %s was removed from %s before it was deleted, as on_destroy_references is \"detach\". Check that %s still works without it.", name, loc, ref, ref))
+	}
 }
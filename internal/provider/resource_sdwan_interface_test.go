@@ -0,0 +1,238 @@
+package pansdwan
+
+import (
+	"context"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestFindInterfaceVsys(t *testing.T) {
+	loc := TemplateLocation("branch")
+	// PAN-OS answers a /@name query with the bare entries
+	_, client := newFakeDevice(t, map[string]string{
+		loc.VsysByImportInterfaceXPath("sdwan.901"): `<entry name="vsys1"/><entry name="vsys2"/>`,
+	})
+	cases := []struct {
+		name    string
+		iface   string
+		current string
+		want    string
+	}{
+		{name: "first vsys", iface: "sdwan.901", want: "vsys1"},
+		{name: "current vsys", iface: "sdwan.901", current: "vsys2", want: "vsys2"},
+		{name: "not imported", iface: "sdwan.902", current: "vsys1"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := findInterfaceVsys(context.Background(), client, loc, tc.iface, tc.current)
+			if err != nil {
+				t.Fatalf("findInterfaceVsys() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("findInterfaceVsys() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFindInterfaceVr(t *testing.T) {
+	loc := NGFWLocation()
+	_, client := newFakeDevice(t, map[string]string{
+		loc.VirtualRouterByInterfaceXPath("sdwan.901"): `<entry name="default"/>`,
+	})
+	for iface, want := range map[string]string{"sdwan.901": "default", "sdwan.902": ""} {
+		got, err := findInterfaceVr(context.Background(), client, loc, iface)
+		if err != nil {
+			t.Fatalf("findInterfaceVr(%q) error = %v", iface, err)
+		}
+		if got != want {
+			t.Errorf("findInterfaceVr(%q) = %q, want %q", iface, got, want)
+		}
+	}
+}
+
+func TestSdwanInterfaceUpgradeStateV0(t *testing.T) {
+	cases := []struct {
+		name     string
+		rawState string
+		want     string
+		comment  string
+	}{
+		{
+			name:     "template",
+			rawState: `{"id":"sdwan.901","template":"t-1","location":[],"name":"sdwan.901","members":["ethernet1/1"],"protocol":"ipv4","comment":"","vsys":"vsys1"}`,
+			want:     "template=t-1:sdwan.901",
+		},
+		{
+			name:     "template with separators",
+			rawState: `{"id":"sdwan.901","template":"t:1=a%","name":"sdwan.901","members":["ethernet1/1"],"protocol":"ipv4","comment":"","vsys":"vsys1","virtual_router":"","zone":""}`,
+			want:     "template=t%3A1%3Da%25:sdwan.901",
+		},
+		{
+			name:     "template stack",
+			rawState: `{"id":"sdwan.901","template":"","location":[{"panorama_template":"","template_stack":"stack-1","ngfw":false}],"name":"sdwan.901","members":["ethernet1/1"],"protocol":"ipv4","vsys":"vsys1"}`,
+			want:     "template_stack=stack-1:sdwan.901",
+		},
+		{
+			name:     "ngfw",
+			rawState: `{"id":"sdwan.901","template":"","location":[{"panorama_template":"","template_stack":"","ngfw":true}],"name":"sdwan.901","members":["ethernet1/1"],"protocol":"ipv4","vsys":"vsys1"}`,
+			want:     "ngfw:sdwan.901",
+		},
+		{
+			name:     "comment",
+			rawState: `{"id":"sdwan.901","template":"t-1","location":[],"name":"sdwan.901","members":["ethernet1/1"],"protocol":"ipv4","comment":"to the hub","vsys":"vsys1"}`,
+			want:     "template=t-1:sdwan.901",
+			comment:  "to the hub",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			attrs := upgradeState(t, &sdwanInterfaceResource{}, 0, tc.rawState)
+			if got := stringAttr(t, attrs, "id"); got != tc.want {
+				t.Errorf("id = %q, want %q", got, tc.want)
+			}
+			if got := stringAttr(t, attrs, "vsys"); got != "vsys1" {
+				t.Errorf("vsys = %q, want %q", got, "vsys1")
+			}
+			// Unset arguments are null, as the plan has them
+			for _, k := range []string{"comment", "virtual_router", "zone"} {
+				if k == "comment" && tc.comment != "" {
+					if got := stringAttr(t, attrs, k); got != tc.comment {
+						t.Errorf("comment = %q, want %q", got, tc.comment)
+					}
+					continue
+				}
+				if !attrs[k].IsNull() {
+					t.Errorf("%s = %v, want null", k, attrs[k])
+				}
+			}
+		})
+	}
+}
+
+func TestSdwanInterfaceDeleteDetach(t *testing.T) {
+	loc := NGFWLocation()
+	device, client := newFakeDevice(t, nil)
+	device.responses = map[string]string{
+		"delete " + loc.SdwanInterfaceXPath("sdwan.901"): referenceError(
+			"vsys -> vsys1 -> import -> network -> interface",
+			"vsys -> vsys1 -> zone -> untrust -> network -> layer3",
+			"vsys -> vsys1 -> rulebase -> sdwan -> rules -> voice -> from -> interface",
+		),
+	}
+	str := func(s string) tftypes.Value { return tftypes.NewValue(tftypes.String, s) }
+	r := &sdwanInterfaceResource{client: client}
+	state := tfsdk.State{Schema: resourceSchema(r), Raw: objectValue(r, map[string]tftypes.Value{
+		"location":              locationBlockValue("", "", true),
+		"name":                  str("sdwan.901"),
+		"vsys":                  str("vsys1"),
+		"zone":                  str("untrust"),
+		"on_destroy_references": str(onDestroyReferencesDetach),
+	})}
+	resp := &resource.DeleteResponse{State: state}
+	r.Delete(context.Background(), resource.DeleteRequest{State: state}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Delete(): %v", resp.Diagnostics)
+	}
+	var got []string
+	for _, d := range resp.Diagnostics.Warnings() {
+		got = append(got, d.Summary())
+	}
+	want := []string{
+		"Detached SD-WAN interface sdwan.901 from vsys import vsys1",
+		"Detached SD-WAN interface sdwan.901 from zone untrust in vsys1",
+		"Detached SD-WAN interface sdwan.901 from SD-WAN policy rule voice in vsys1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Delete() warnings =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBuildSdwanInterfaceElementRoundTrip(t *testing.T) {
+	comments := []string{
+		"",
+		"to the hub",
+		"R&D <primary>",
+		`branch's "main" link`,
+		"50%20off",
+		"ends a CDATA ]]> section",
+		"  padded\twith whitespace \n",
+		"unicode → hub",
+	}
+	members := []string{"ethernet1/1", "ethernet1/2.100"}
+	for _, comment := range comments {
+		element, err := buildSdwanInterfaceElement("sdwan.901", "ipv4", comment, members)
+		if err != nil {
+			t.Fatalf("buildSdwanInterfaceElement(%q) error = %v", comment, err)
+		}
+		// Read decodes the entry from the result of a get
+		var resp sdwanInterface
+		body := `<response status="success" code="19"><result total-count="1" count="1">` + element + `</result></response>`
+		if err := decodeXML([]byte(body), &resp); err != nil {
+			t.Fatalf("decode %s: %v", element, err)
+		}
+		entry := resp.Result.Entry
+		if entry.Comment != comment {
+			t.Errorf("comment %q read back as %q from %s", comment, entry.Comment, element)
+		}
+		if entry.Name != "sdwan.901" || entry.Protocol != "ipv4" || !reflect.DeepEqual(entry.Members, members) {
+			t.Errorf("entry read back as %+v from %s", entry, element)
+		}
+	}
+}
+
+func TestSdwanInterfaceCreateAndUpdateWriteTheSameEntry(t *testing.T) {
+	loc := TemplateLocation("branch")
+	device, client := newFakeDevice(t, nil)
+	r := &sdwanInterfaceResource{client: client}
+	s := resourceSchema(r)
+	str := func(s string) tftypes.Value { return tftypes.NewValue(tftypes.String, s) }
+	comment := `R&D <"hub"> 50%20 ]]> `
+	value := objectValue(r, map[string]tftypes.Value{
+		"template":              str("branch"),
+		"name":                  str("sdwan.901"),
+		"members":               tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{str("ethernet1/1")}),
+		"protocol":              str("ipv4"),
+		"comment":               str(comment),
+		"vsys":                  str("vsys1"),
+		"on_destroy_references": str(onDestroyReferencesFail),
+	})
+	ctx := context.Background()
+	plan := tfsdk.Plan{Schema: s, Raw: value}
+	state := tfsdk.State{Schema: s, Raw: value}
+
+	createResp := &resource.CreateResponse{State: tfsdk.State{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)}}
+	r.Create(ctx, resource.CreateRequest{Plan: plan}, createResp)
+	updateResp := &resource.UpdateResponse{State: state}
+	r.Update(ctx, resource.UpdateRequest{Plan: plan, State: state}, updateResp)
+	if createResp.Diagnostics.HasError() || updateResp.Diagnostics.HasError() {
+		t.Fatalf("Create(): %v, Update(): %v", createResp.Diagnostics, updateResp.Diagnostics)
+	}
+
+	// Both send a multi-config batch, find the entry written by each
+	var entries []string
+	for _, req := range device.requests {
+		var batch multiConfigRequest
+		if err := xml.Unmarshal([]byte(req.Element), &batch); err != nil {
+			t.Fatalf("%s request: %v", req.Action, err)
+		}
+		for _, op := range batch.Ops {
+			if op.XPath == loc.SdwanInterfaceUnitsXPath() || op.XPath == loc.SdwanInterfaceXPath("sdwan.901") {
+				entries = append(entries, op.Element)
+			}
+		}
+	}
+	if len(entries) != 2 || entries[0] != entries[1] {
+		t.Fatalf("Create and Update wrote %q, want the same entry", entries)
+	}
+	var entry sdwanInterfaceEntry
+	if err := xml.Unmarshal([]byte(entries[0]), &entry); err != nil || entry.Comment != comment {
+		t.Errorf("comment read back as %q, %v, want %q", entry.Comment, err, comment)
+	}
+}
@@ -3,13 +3,16 @@ package pansdwan
 import (
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // XML Response Structs
@@ -19,165 +22,340 @@ type zoneInterfaces struct {
 	Status  string   `xml:"status,attr"`
 	Code    string   `xml:"code,attr"`
 	Result  struct {
-		Text       string `xml:",chardata"`
-		TotalCount string `xml:"total-count,attr"`
-		Count      string `xml:"count,attr"`
-		Layer3     struct {
-			Text   string   `xml:",chardata"`
-			Member []string `xml:"member"`
-		} `xml:"layer3"`
+		Text       string     `xml:",chardata"`
+		TotalCount string     `xml:"total-count,attr"`
+		Count      string     `xml:"count,attr"`
+		Layer3     memberList `xml:"layer3"`
 	} `xml:"result"`
 }
 
-func resourceZoneEntry() *schema.Resource {
-	return &schema.Resource{
-		CreateContext: resourceZoneEntryCreate,
-		ReadContext:   resourceZoneEntryRead,
-		UpdateContext: resourceZoneEntryUpdate,
-		DeleteContext: resourceZoneEntryDelete,
-		// Schema for the resource
-		Schema: map[string]*schema.Schema{
-			"template": {
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+type entryNames struct {
+	XMLName xml.Name `xml:"response"`
+	Result  struct {
+		Entries []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"entry"`
+	} `xml:"result"`
+}
+
+var (
+	_ resource.ResourceWithConfigure      = &zoneEntryResource{}
+	_ resource.ResourceWithImportState    = &zoneEntryResource{}
+	_ resource.ResourceWithUpgradeState   = &zoneEntryResource{}
+	_ resource.ResourceWithValidateConfig = &zoneEntryResource{}
+)
+
+type zoneEntryResource struct {
+	client *APIClient
+}
+
+type zoneEntryModel struct {
+	ID        types.String `tfsdk:"id"`
+	Template  types.String `tfsdk:"template"`
+	Location  types.List   `tfsdk:"location"`
+	Name      types.String `tfsdk:"name"`
+	Interface types.String `tfsdk:"interface"`
+	Vsys      types.String `tfsdk:"vsys"`
+}
+
+func newZoneEntryResource() resource.Resource {
+	return &zoneEntryResource{}
+}
+
+func (r *zoneEntryResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_l3_zone_entry"
+}
+
+func (r *zoneEntryResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	// Schema for the resource, the same as when it was an SDK resource so existing state is read as is
+	resp.Schema = schema.Schema{
+		Version: 1,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 			},
-			"name": {
-				Type:     schema.TypeString,
+			"template": templateAttribute(),
+			"name": schema.StringAttribute{
 				Required: true,
-				ForceNew: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
-			"interface": {
-				Type:     schema.TypeString,
+			"interface": schema.StringAttribute{
 				Required: true,
-				ForceNew: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
-			"vsys": {
-				Type:     schema.TypeString,
+			"vsys": schema.StringAttribute{
 				Required: true,
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"location": locationBlock(),
+		},
 	}
 }
 
-func resourceZoneEntryCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*APIClient)
-	// Construct the URL to create the sdwan interface
-	req_url := fmt.Sprintf("https://%s/api/?type=config&action=set&xpath=/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='%s']/zone/entry[@name='%s']/network/layer3&element=<member>%s</member>",
-		client.Host, url.QueryEscape(d.Get("template").(string)), url.QueryEscape(d.Get("vsys").(string)), url.QueryEscape(d.Get("name").(string)), url.QueryEscape(d.Get("interface").(string)))
+func (r *zoneEntryResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = configureClient(req.ProviderData, &resp.Diagnostics)
+}
 
-	req, _ := http.NewRequest("GET", req_url, nil)
-	apiKey, _ := getAPIKey(client.Host, client.Username, client.Password, client.SkipSSLVerification)
-	req.Header.Set("Content-Type", "application/xml")
-	req.Header.Set("X-PAN-KEY", apiKey)
-	httpClient := buildHttpClient(client.SkipSSLVerification)
+func (r *zoneEntryResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config zoneEntryModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(validateLocationConfig(ctx, config.Template, config.Location)...)
+}
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return diag.FromErr(err)
+func (r *zoneEntryResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !clientConfigured(r.client, &resp.Diagnostics) {
+		return
 	}
-	defer resp.Body.Close()
-	// Read and check response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return diag.FromErr(err)
+	var plan zoneEntryModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	if resp.StatusCode != 200 {
-		return diag.Errorf("Failed to add interface to Zone: %s", string(body))
+	loc, err := locationFromModel(ctx, plan.Template, plan.Location)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid location", err.Error())
+		return
 	}
-	// Catch failures in the response
-	resp_err := checkXMLResponse(body)
-	if resp_err != nil {
-		return diag.FromErr(resp_err)
+	vsys, zone, iface := plan.Vsys.ValueString(), plan.Name.ValueString(), plan.Interface.ValueString()
+	// Construct the xpath to add the interface to the zone
+	xpath := loc.ZoneLayer3XPath(vsys, zone)
+
+	if _, err := r.client.Set(ctx, xpath, memberElement(iface)); err != nil {
+		resp.Diagnostics.Append(diagFromErr("Failed to add interface to Zone", err)...)
+		return
 	}
-	// Set the ID back to terraform as the name of the interface
-	d.SetId(fmt.Sprintf("%s-%s-%s", d.Get("template").(string), d.Get("name").(string), d.Get("interface").(string)))
-	// Return nothing as we only return the error if there was one
-	return nil
+	// Set the ID back to terraform as the location, vsys, zone and interface
+	plan.ID = types.StringValue(zoneEntryID(loc, vsys, zone, iface))
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
 }
 
-func resourceZoneEntryRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*APIClient)
-	// Construct the URL to get the zone interfaces
-	req_url := fmt.Sprintf("https://%s/api/?type=config&action=get&xpath=/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='%s']/zone/entry[@name='%s']/network/layer3",
-		client.Host, url.QueryEscape(d.Get("template").(string)), url.QueryEscape(d.Get("vsys").(string)), url.QueryEscape(d.Get("name").(string)))
-
-	req, _ := http.NewRequest("GET", req_url, nil)
-	apiKey, _ := getAPIKey(client.Host, client.Username, client.Password, client.SkipSSLVerification)
-	req.Header.Set("Content-Type", "application/xml")
-	req.Header.Set("X-PAN-KEY", apiKey)
-	httpClient := buildHttpClient(client.SkipSSLVerification)
-
-	resp, err := httpClient.Do(req)
+func (r *zoneEntryResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if !clientConfigured(r.client, &resp.Diagnostics) {
+		return
+	}
+	var state zoneEntryModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	loc, err := locationFromModel(ctx, state.Template, state.Location)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Invalid location", err.Error())
+		return
 	}
-	defer resp.Body.Close()
+	state.Template, state.Location = normalizeLocation(state.Template, state.Location, loc)
+	vsys, zone, iface := state.Vsys.ValueString(), state.Name.ValueString(), state.Interface.ValueString()
+	// Construct the xpath to get the zone interfaces
+	xpath := loc.ZoneLayer3XPath(vsys, zone)
 
-	body, _ := io.ReadAll(resp.Body)
+	body, err := r.client.Get(ctx, xpath)
+	if errors.Is(err, ErrObjectNotPresent) {
+		// This means the zone has no layer3 interfaces so look for where the interface has gone
+		resp.Diagnostics.Append(stackObjectNotPresent(ctx, r.client, loc, fmt.Sprintf("Zone %s", zone), func(l Location) string {
+			return l.ZoneLayer3XPath(vsys, zone)
+		})...)
+		r.entryMoved(ctx, loc, &state, resp)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.Append(diagFromErr("Error getting zone interfaces", err)...)
+		return
+	}
 	var zone_ifaces_xml_resp zoneInterfaces
-	if err := xml.Unmarshal(body, &zone_ifaces_xml_resp); err != nil {
-		panic(err)
+	if err := decodeXML(body, &zone_ifaces_xml_resp); err != nil {
+		resp.Diagnostics.Append(diagFromErr("Error parsing zone interfaces", err)...)
+		return
 	}
-	// Catch failures in the response
-	if resp.StatusCode != 200 {
-		return diag.Errorf("Error getting zone interfaces: %s", string(body))
+	// Check the interface is still a member of the zone
+	found := false
+	for _, member := range zone_ifaces_xml_resp.Result.Layer3.Members {
+		if member == iface {
+			found = true
+		}
 	}
-	if zone_ifaces_xml_resp.Status == "error" {
-		return diag.Errorf("Error getting zone interfaces: %s", string(body))
+	if !found {
+		// The interface has been removed from the zone outside of terraform, or the device has it in another vsys
+		r.entryMoved(ctx, loc, &state, resp)
+		return
 	}
-	// Check if the interface exists
-	if zone_ifaces_xml_resp.Code == "7" {
-		// This means the interface does not exist set the ID to empty and return
-		d.SetId("")
-		return nil
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+// entryMoved is called by Read when the interface has disappeared from the
+// configured zone. If it is in the same zone of another vsys the device
+// disagrees with the state about the vsys, so the entry is kept with the
+// device's vsys and Update moves it back. Otherwise the entry is removed from
+// the state, with a warning if the interface has been moved rather than
+// removed so the plan to add it back is not a surprise.
+func (r *zoneEntryResource) entryMoved(ctx context.Context, loc Location, state *zoneEntryModel, resp *resource.ReadResponse) {
+	iface := state.Interface.ValueString()
+	vsys, zone, err := findInterfaceZone(ctx, r.client, loc, iface)
+	if err != nil {
+		resp.Diagnostics.Append(diagFromErr(fmt.Sprintf("Failed to look up the zone of interface %s", iface), err)...)
+		return
+	}
+	if vsys != "" && zone == state.Name.ValueString() {
+		resp.Diagnostics.AddWarning(fmt.Sprintf("Zone entry for interface %s is in a different vsys", iface),
+			fmt.Sprintf("The state has interface %s in zone %s of vsys %s in %s, but the device has it in zone %s of vsys %s. Applying will move it back to vsys %s.",
+				iface, zone, state.Vsys.ValueString(), loc, zone, vsys, state.Vsys.ValueString()))
+		state.Vsys = types.StringValue(vsys)
+		state.ID = types.StringValue(zoneEntryID(loc, vsys, zone, iface))
+		resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+		return
+	}
+	resp.State.RemoveResource(ctx)
+	if vsys != "" {
+		resp.Diagnostics.AddWarning(fmt.Sprintf("Interface %s has moved to another zone", iface),
+			fmt.Sprintf("Interface %s is no longer in zone %s of vsys %s in %s, it is in zone %s of vsys %s. Applying will try to add it back to zone %s.",
+				iface, state.Name.ValueString(), state.Vsys.ValueString(), loc, zone, vsys, state.Name.ValueString()))
 	}
-	// Set the resource data back to terraform
-	d.Set("template", d.Get("template").(string))
-	d.Set("name", d.Get("name").(string))
-	d.Set("interface", d.Get("interface").(string))
-	d.Set("vsys", d.Get("vsys").(string))
-	// Return nothing as we only return the error if there was one
-	return nil
 }
 
-func resourceZoneEntryUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	// Any updates force recreate of the resource
-	return nil
+// findInterfaceZone returns the vsys and zone the interface is a layer3
+// member of in the location, or empty strings if it is in none.
+func findInterfaceZone(ctx context.Context, client *APIClient, loc Location, iface string) (string, string, error) {
+	// Find the vsys first, as the zone names alone do not say which vsys they are in
+	vsys, err := firstEntryName(ctx, client, loc.VsysByZoneInterfaceXPath(iface))
+	if err != nil || vsys == "" {
+		return "", "", err
+	}
+	zone, err := firstEntryName(ctx, client, loc.ZoneByInterfaceXPath(vsys, iface))
+	if err != nil || zone == "" {
+		return "", "", err
+	}
+	return vsys, zone, nil
 }
 
-func resourceZoneEntryDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*APIClient)
+// firstEntryName returns the first name returned by an xpath ending in
+// /@name, or an empty string if nothing matches.
+func firstEntryName(ctx context.Context, client *APIClient, xpath string) (string, error) {
+	body, err := client.Get(ctx, xpath)
+	if errors.Is(err, ErrObjectNotPresent) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var resp entryNames
+	if err := decodeXML(body, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Result.Entries) > 0 {
+		return resp.Result.Entries[0].Name, nil
+	}
+	return "", nil
+}
 
-	// Construct the URL to delete the interface from the Zone
-	req_url := fmt.Sprintf("https://%s/api/?type=config&action=delete&xpath=/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='%s']/zone/entry[@name='%s']/network/layer3/member[text()='%s']",
-		client.Host, url.QueryEscape(d.Get("template").(string)), url.QueryEscape(d.Get("vsys").(string)), url.QueryEscape(d.Get("name").(string)), url.QueryEscape(d.Get("interface").(string)))
+// ImportState accepts an ID of the form <location>:<vsys>:<zone>:<interface>,
+// for example `branch:vsys1:untrust:sdwan.901`. Read then checks the entry exists.
+// As for SD-WAN interfaces, the location is imported as the location block.
+func (r *zoneEntryResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	loc, parts, err := splitImportID(req.ID, "<location>:<vsys>:<zone>:<interface>", 3)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid import ID", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("location"), locationValue(loc))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("vsys"), parts[0])...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), parts[1])...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("interface"), parts[2])...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), zoneEntryID(loc, parts[0], parts[1], parts[2]))...)
+}
 
-	req, _ := http.NewRequest("GET", req_url, nil)
-	apiKey, _ := getAPIKey(client.Host, client.Username, client.Password, client.SkipSSLVerification)
-	req.Header.Set("Content-Type", "application/xml")
-	req.Header.Set("X-PAN-KEY", apiKey)
-	httpClient := buildHttpClient(client.SkipSSLVerification)
-	resp, err := httpClient.Do(req)
+// UpgradeState upgrades state from before IDs were structured, when the ID
+// was the template, zone and interface joined with hyphens.
+func (r *zoneEntryResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: {
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				upgradeRawState(ctx, r, req, resp, zoneEntryStateUpgradeV0)
+			},
+		},
+	}
+}
+
+// zoneEntryStateUpgradeV0 rebuilds the ID from the attributes, as the hyphen
+// joined ID cannot be split when names contain hyphens.
+func zoneEntryStateUpgradeV0(rawState map[string]interface{}) (map[string]interface{}, error) {
+	loc, err := locationFromRawState(rawState)
 	if err != nil {
-		return diag.FromErr(err)
+		return nil, err
+	}
+	vsys, _ := rawState["vsys"].(string)
+	zone, _ := rawState["name"].(string)
+	iface, _ := rawState["interface"].(string)
+	rawState["id"] = zoneEntryID(loc, vsys, zone, iface)
+	return rawState, nil
+}
+
+func (r *zoneEntryResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !clientConfigured(r.client, &resp.Diagnostics) {
+		return
+	}
+	var plan, state zoneEntryModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	defer resp.Body.Close()
-	// Read and check response body
-	body, err := io.ReadAll(resp.Body)
+	loc, err := locationFromModel(ctx, plan.Template, plan.Location)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Invalid location", err.Error())
+		return
 	}
-	if resp.StatusCode != 200 {
-		return diag.Errorf("Failed to remove interface from Zone: %s", string(body))
+	zone, iface := plan.Name.ValueString(), plan.Interface.ValueString()
+	// The vsys is the only attribute that can change without recreating the entry
+	vsys_before, vsys_after := state.Vsys.ValueString(), plan.Vsys.ValueString()
+	if vsys_before != vsys_after {
+		tflog.Info(ctx, "Moving zone entry to another vsys", map[string]interface{}{
+			"vsys_before": vsys_before,
+			"vsys_after":  vsys_after,
+		})
+		// Remove the interface from the zone in the old vsys and add it to the zone in the new one in one batch
+		ops := []ConfigOp{
+			DeleteOp(memberXPath(loc.ZoneLayer3XPath(vsys_before, zone), iface)),
+			SetOp(loc.ZoneLayer3XPath(vsys_after, zone), memberElement(iface)),
+		}
+		resp.Diagnostics.Append(applyConfigOps(ctx, r.client, "Failed to move interface to the Zone in the new vsys", ops...)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
 	}
-	// Catch failures in the response
-	resp_err := checkXMLResponse(body)
-	if resp_err != nil {
-		return diag.FromErr(resp_err)
+	// The vsys is part of the ID so set it again
+	plan.ID = types.StringValue(zoneEntryID(loc, vsys_after, zone, iface))
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *zoneEntryResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !clientConfigured(r.client, &resp.Diagnostics) {
+		return
+	}
+	var state zoneEntryModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	loc, err := locationFromModel(ctx, state.Template, state.Location)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid location", err.Error())
+		return
+	}
+	// Construct the xpath to delete the interface from the Zone
+	xpath := memberXPath(loc.ZoneLayer3XPath(state.Vsys.ValueString(), state.Name.ValueString()), state.Interface.ValueString())
+
+	if _, err := r.client.Delete(ctx, xpath); err != nil && !errors.Is(err, ErrObjectNotPresent) {
+		resp.Diagnostics.Append(diagFromErr("Failed to remove interface from Zone", err)...)
 	}
-	// Set the ID back to empty as the interface has been deleted
-	d.SetId("")
-	// Return nothing as we only return the error if there was one
-	return nil
 }
@@ -0,0 +1,85 @@
+package pansdwan
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+)
+
+func TestFindInterfaceZone(t *testing.T) {
+	loc := TemplateStackLocation("branch-stack")
+	_, client := newFakeDevice(t, map[string]string{
+		loc.VsysByZoneInterfaceXPath("sdwan.901"):            `<entry name="vsys2"/>`,
+		loc.ZoneByInterfaceXPath("vsys2", "sdwan.901"):       `<entry name="sdwan-hub"/>`,
+		loc.VsysByZoneInterfaceXPath("sdwan.902"):            `<entry name="vsys1"/>`,
+		loc.VsysByZoneInterfaceXPath("sdwan's \"hub\""):      `<entry name="vsys1"/>`,
+		loc.ZoneByInterfaceXPath("vsys1", "sdwan's \"hub\""): `<entry name="untrust"/>`,
+	})
+	cases := []struct {
+		name     string
+		iface    string
+		wantVsys string
+		wantZone string
+	}{
+		{name: "in a zone", iface: "sdwan.901", wantVsys: "vsys2", wantZone: "sdwan-hub"},
+		{name: "quoted interface", iface: "sdwan's \"hub\"", wantVsys: "vsys1", wantZone: "untrust"},
+		// The zone was changed between the two queries
+		{name: "zone gone", iface: "sdwan.902"},
+		{name: "in no zone", iface: "sdwan.903"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			vsys, zone, err := findInterfaceZone(context.Background(), client, loc, tc.iface)
+			if err != nil {
+				t.Fatalf("findInterfaceZone() error = %v", err)
+			}
+			if vsys != tc.wantVsys || zone != tc.wantZone {
+				t.Errorf("findInterfaceZone() = %q, %q, want %q, %q", vsys, zone, tc.wantVsys, tc.wantZone)
+			}
+		})
+	}
+}
+
+func TestZoneEntryUpgradeStateV0(t *testing.T) {
+	cases := []struct {
+		name     string
+		rawState string
+		want     string
+	}{
+		{
+			name:     "template",
+			rawState: `{"id":"t-1-untrust-sdwan.901","template":"t-1","location":[],"vsys":"vsys1","name":"untrust","interface":"sdwan.901"}`,
+			want:     "template=t-1:vsys1:untrust:sdwan.901",
+		},
+		{
+			name:     "names with separators",
+			rawState: `{"id":"t-1-a:b-sdwan.901","template":"t-1","vsys":"vsys1","name":"a:b=c","interface":"sdwan.901"}`,
+			want:     "template=t-1:vsys1:a%3Ab%3Dc:sdwan.901",
+		},
+		{
+			name:     "template stack",
+			rawState: `{"id":"-untrust-sdwan.901","template":"","location":[{"panorama_template":"","template_stack":"stack-1","ngfw":false}],"vsys":"vsys1","name":"untrust","interface":"sdwan.901"}`,
+			want:     "template_stack=stack-1:vsys1:untrust:sdwan.901",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			attrs := upgradeState(t, &zoneEntryResource{}, 0, tc.rawState)
+			if got := stringAttr(t, attrs, "id"); got != tc.want {
+				t.Errorf("id = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestZoneEntryUpgradeStateV0WithoutLocation(t *testing.T) {
+	ctx := context.Background()
+	req := resource.UpgradeStateRequest{RawState: &tfprotov6.RawState{JSON: []byte(`{"id":"untrust-sdwan.901","template":"","vsys":"vsys1","name":"untrust","interface":"sdwan.901"}`)}}
+	var resp resource.UpgradeStateResponse
+	(&zoneEntryResource{}).UpgradeState(ctx)[0].StateUpgrader(ctx, req, &resp)
+	if !resp.Diagnostics.HasError() {
+		t.Error("upgrade state succeeded, want an error for state without a location")
+	}
+}
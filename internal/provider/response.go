@@ -0,0 +1,62 @@
+package pansdwan
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// maxSnippetLength bounds how much of an unexpected body is put in an error.
+const maxSnippetLength = 512
+
+// checkResponse is the single decoder every XML API response goes through.
+// It checks the HTTP status, the content type and that the body is a valid
+// PAN-OS <response>, and turns any failure into an *APIError carrying a
+// snippet of the body. It never panics, whatever the device or a proxy in
+// front of it sends back.
+func checkResponse(status int, contentType string, body []byte) error {
+	if strings.Contains(strings.ToLower(contentType), "html") {
+		return unexpectedResponse(status, fmt.Sprintf("received %s instead of XML, a proxy or login page may be in the way", contentType), body)
+	}
+	var xmlResp XMLAPIResponse
+	if err := xml.Unmarshal(body, &xmlResp); err != nil {
+		if status != 200 {
+			return unexpectedResponse(status, fmt.Sprintf("HTTP status %d", status), body)
+		}
+		return unexpectedResponse(status, fmt.Sprintf("invalid XML response: %s", err), body)
+	}
+	if status != 200 || xmlResp.Status == "error" {
+		return newAPIError(status, xmlResp.Code, xmlResp.lines())
+	}
+	return nil
+}
+
+// decodeXML unmarshals a response body which has already passed
+// checkResponse into v, reporting a failure as an *APIError instead of
+// leaving it to the caller.
+func decodeXML(body []byte, v interface{}) error {
+	if err := xml.Unmarshal(body, v); err != nil {
+		return unexpectedResponse(200, fmt.Sprintf("failed to parse response: %s", err), body)
+	}
+	return nil
+}
+
+func unexpectedResponse(status int, reason string, body []byte) *APIError {
+	return &APIError{
+		HTTPStatus: status,
+		Lines:      []string{reason + ".", fmt.Sprintf("Response body: %s", bodySnippet(body))},
+		kind:       ErrUnexpectedResponse,
+	}
+}
+
+// bodySnippet returns the start of body, collapsed onto one line, for errors.
+func bodySnippet(body []byte) string {
+	snippet := strings.Join(strings.Fields(string(body)), " ")
+	if snippet == "" {
+		return "(empty)"
+	}
+	if len(snippet) > maxSnippetLength {
+		return snippet[:maxSnippetLength] + "... (truncated)"
+	}
+	return snippet
+}
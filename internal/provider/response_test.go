@@ -0,0 +1,161 @@
+package pansdwan
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckResponse(t *testing.T) {
+	loginPage := `<!DOCTYPE html><html><head><title>Login</title></head><body><form action="/php/login.php"></form></body></html>`
+	cases := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantKind    error
+		wantStatus  int
+		// wantLine is expected in the message, usually the body snippet
+		wantLine string
+	}{
+		{
+			name:        "success",
+			status:      200,
+			contentType: "application/xml; charset=UTF-8",
+			body:        `<response status="success" code="19"><result/></response>`,
+		},
+		{
+			name:        "HTML login page",
+			status:      200,
+			contentType: "text/html; charset=UTF-8",
+			body:        loginPage,
+			wantKind:    ErrUnexpectedResponse,
+			wantStatus:  200,
+			wantLine:    "Response body: <!DOCTYPE html><html><head><title>Login</title>",
+		},
+		{
+			name:        "HTML without a content type",
+			status:      200,
+			contentType: "",
+			body:        loginPage,
+			wantKind:    ErrUnexpectedResponse,
+			wantStatus:  200,
+			wantLine:    "invalid XML response",
+		},
+		{
+			name:        "truncated response",
+			status:      200,
+			contentType: "application/xml",
+			body:        `<response status="success"><result><entry name="sdwan.9`,
+			wantKind:    ErrUnexpectedResponse,
+			wantStatus:  200,
+			wantLine:    `Response body: <response status="success"><result><entry name="sdwan.9`,
+		},
+		{
+			name:        "truncated start of a response",
+			status:      200,
+			contentType: "application/xml",
+			body:        `<response`,
+			wantKind:    ErrUnexpectedResponse,
+			wantStatus:  200,
+			wantLine:    "Response body: <response",
+		},
+		{
+			name:        "empty body",
+			status:      200,
+			contentType: "application/xml",
+			body:        "",
+			wantKind:    ErrUnexpectedResponse,
+			wantStatus:  200,
+			wantLine:    "Response body: (empty)",
+		},
+		{
+			name:        "502 without XML",
+			status:      502,
+			contentType: "text/plain",
+			body:        "Bad Gateway\n",
+			wantKind:    ErrUnexpectedResponse,
+			wantStatus:  502,
+			wantLine:    "HTTP status 502.",
+		},
+		{
+			name:        "502 with an HTML page",
+			status:      502,
+			contentType: "text/html",
+			body:        "<html><body><h1>502 Bad Gateway</h1></body></html>",
+			wantKind:    ErrUnexpectedResponse,
+			wantStatus:  502,
+			wantLine:    "Response body: <html><body><h1>502 Bad Gateway</h1></body></html>",
+		},
+		{
+			name:        "502 with XML",
+			status:      502,
+			contentType: "application/xml",
+			body:        `<response status="error"><msg><line>Server error: management server is not ready</line></msg></response>`,
+			wantKind:    ErrUnexpectedResponse,
+			wantStatus:  502,
+			wantLine:    "Server error: management server is not ready",
+		},
+		{
+			name:        "403 with XML",
+			status:      403,
+			contentType: "application/xml",
+			body:        `<response status="error" code="403"><result><msg>Invalid Credential</msg></result></response>`,
+			wantKind:    ErrAuthFailed,
+			wantStatus:  403,
+			wantLine:    "Invalid Credential",
+		},
+		{
+			name:        "error status",
+			status:      200,
+			contentType: "application/xml",
+			body:        `<response status="error" code="7"><msg><line>No such node</line></msg></response>`,
+			wantKind:    ErrObjectNotPresent,
+			wantStatus:  200,
+			wantLine:    "No such node",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkResponse(tc.status, tc.contentType, []byte(tc.body))
+			if tc.wantKind == nil {
+				if err != nil {
+					t.Fatalf("checkResponse() error = %v", err)
+				}
+				return
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("checkResponse() error = %v, want an *APIError", err)
+			}
+			if !errors.Is(err, tc.wantKind) || apiErr.HTTPStatus != tc.wantStatus {
+				t.Errorf("checkResponse() = %v (HTTP %d), want %v (HTTP %d)", apiErr.kind, apiErr.HTTPStatus, tc.wantKind, tc.wantStatus)
+			}
+			if !strings.Contains(apiErr.Message(), tc.wantLine) {
+				t.Errorf("checkResponse() message = %q, want it to contain %q", apiErr.Message(), tc.wantLine)
+			}
+		})
+	}
+}
+
+func TestBodySnippet(t *testing.T) {
+	long := strings.Repeat("<entry/>", maxSnippetLength)
+	cases := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "empty", body: "", want: "(empty)"},
+		{name: "whitespace", body: " \n\t", want: "(empty)"},
+		{name: "collapsed onto one line", body: "<response>\n  <msg>  failed </msg>\n</response>\n", want: "<response> <msg> failed </msg> </response>"},
+		{name: "at the limit", body: long[:maxSnippetLength], want: long[:maxSnippetLength]},
+		{name: "over the limit", body: long, want: long[:maxSnippetLength] + "... (truncated)"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := bodySnippet([]byte(tc.body)); got != tc.want {
+				t.Errorf("bodySnippet() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
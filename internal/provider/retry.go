@@ -0,0 +1,127 @@
+package pansdwan
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultMaxBackoff = 30 * time.Second
+	minBackoff        = 500 * time.Millisecond
+)
+
+// PAN-OS XML API response codes which indicate a transient condition on the
+// device rather than a problem with the request itself.
+var retryableResponseCodes = map[string]bool{
+	"2":  true, // Internal error
+	"3":  true, // Internal error
+	"4":  true, // Internal error
+	"5":  true, // Internal error
+	"11": true, // Internal error
+	"21": true, // Internal error
+}
+
+// Messages PAN-OS returns while a commit is running or another admin holds a
+// lock. These are reported with a non specific code so match on the text.
+var retryableResponseMessages = []string{
+	"commit is in progress",
+	"commit in progress",
+	"another commit",
+	"config lock",
+	"config is locked",
+	"commit lock",
+	"pending jobs",
+}
+
+// retryableError marks an error from a single attempt as safe to retry.
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+
+func (e *retryableError) Unwrap() error { return e.err }
+
+// withRetry runs fn until it succeeds, returns an error that is not retryable
+// or the client's retry budget is spent. The wait between attempts grows
+// exponentially up to MaxBackoff, with jitter so parallel resources do not
+// retry in lockstep.
+func (c *APIClient) withRetry(ctx context.Context, fn func(attempt int) ([]byte, error)) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		body, err := fn(attempt)
+		var retryErr *retryableError
+		if !errors.As(err, &retryErr) {
+			return body, err
+		}
+		if attempt >= c.MaxRetries {
+			return body, retryErr.err
+		}
+		select {
+		case <-ctx.Done():
+			return body, ctx.Err()
+		case <-time.After(backoff(attempt, c.MaxBackoff)):
+		}
+	}
+}
+
+// backoff returns the wait before retry number attempt, between half and all
+// of the exponential delay.
+func backoff(attempt int, maxBackoff time.Duration) time.Duration {
+	d := minBackoff << uint(attempt)
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
+	}
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// isRetryableNetError reports whether a transport error is likely transient.
+func isRetryableNetError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isRetryableStatus reports whether an HTTP status is worth retrying.
+func isRetryableStatus(status int) bool {
+	return status == 429 || status >= 500
+}
+
+// isRetryableResponse reports whether a PAN-OS error response describes a
+// transient condition such as a commit in progress or a held config lock.
+func isRetryableResponse(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if retryableResponseCodes[apiErr.Code] {
+		return true
+	}
+	for _, line := range apiErr.Lines {
+		line = strings.ToLower(line)
+		for _, msg := range retryableResponseMessages {
+			if strings.Contains(line, msg) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package pansdwan
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingDevice answers every request with the response for its attempt
+// number, repeating the last one once they run out.
+type countingDevice struct {
+	responses []countedResponse
+	requests  atomic.Int32
+	answered  chan struct{}
+}
+
+type countedResponse struct {
+	status int
+	body   string
+}
+
+func (d *countingDevice) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := int(d.requests.Add(1)) - 1
+	resp := d.responses[min(n, len(d.responses)-1)]
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(resp.status)
+	w.Write([]byte(resp.body))
+	if d.answered != nil {
+		select {
+		case d.answered <- struct{}{}:
+		default:
+		}
+	}
+}
+
+const (
+	successResponse     = `<response status="success"><result><system><hostname>fw</hostname></system></result></response>`
+	unavailableResponse = `<response status="error"><msg><line>Service unavailable</line></msg></response>`
+)
+
+func TestWithRetry(t *testing.T) {
+	cases := []struct {
+		name         string
+		maxRetries   int
+		responses    []countedResponse
+		wantRequests int32
+		wantKind     error
+	}{
+		{
+			name:         "retries up to MaxRetries",
+			maxRetries:   2,
+			responses:    []countedResponse{{503, unavailableResponse}},
+			wantRequests: 3,
+			wantKind:     ErrUnexpectedResponse,
+		},
+		{
+			name:         "no retries",
+			maxRetries:   0,
+			responses:    []countedResponse{{503, unavailableResponse}},
+			wantRequests: 1,
+			wantKind:     ErrUnexpectedResponse,
+		},
+		{
+			name:         "succeeds after a 502",
+			maxRetries:   3,
+			responses:    []countedResponse{{502, "Bad Gateway"}, {200, successResponse}},
+			wantRequests: 2,
+		},
+		{
+			name:         "no retry on 403",
+			maxRetries:   3,
+			responses:    []countedResponse{{403, `<response status="error" code="403"><result><msg>Invalid credentials.</msg></result></response>`}},
+			wantRequests: 1,
+			wantKind:     ErrAuthFailed,
+		},
+		{
+			name:         "no retry on insufficient privileges",
+			maxRetries:   3,
+			responses:    []countedResponse{{200, `<response status="error" code="16"><msg><line>Unauthorized request</line></msg></response>`}},
+			wantRequests: 1,
+			wantKind:     ErrInsufficientPrivileges,
+		},
+		{
+			name:         "no retry on an invalid object",
+			maxRetries:   3,
+			responses:    []countedResponse{{200, `<response status="error" code="12"><msg><line>zone is invalid</line></msg></response>`}},
+			wantRequests: 1,
+			wantKind:     ErrInvalidObject,
+		},
+		{
+			name:       "retries while a commit is in progress",
+			maxRetries: 3,
+			responses: []countedResponse{
+				{200, `<response status="error" code="13"><msg><line>Commit is in progress. Please try again later</line></msg></response>`},
+				{200, `<response status="error"><msg><line>Another commit or validate is in progress. Please try again later</line></msg></response>`},
+				{200, successResponse},
+			},
+			wantRequests: 3,
+		},
+		{
+			name:         "retries an internal error",
+			maxRetries:   3,
+			responses:    []countedResponse{{200, `<response status="error" code="5"><msg><line>Internal error</line></msg></response>`}, {200, successResponse}},
+			wantRequests: 2,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			device := &countingDevice{responses: tc.responses}
+			client := newTestClient(t, device)
+			client.MaxRetries = tc.maxRetries
+			client.MaxBackoff = time.Millisecond
+			_, err := client.Op(context.Background(), "<show><system><info/></system></show>")
+			if tc.wantKind == nil && err != nil {
+				t.Errorf("Op() error = %v", err)
+			}
+			if tc.wantKind != nil && !errors.Is(err, tc.wantKind) {
+				t.Errorf("Op() error = %v, want %v", err, tc.wantKind)
+			}
+			if got := device.requests.Load(); got != tc.wantRequests {
+				t.Errorf("Op() made %d requests, want %d", got, tc.wantRequests)
+			}
+		})
+	}
+}
+
+func TestWithRetryCancelledDuringBackoff(t *testing.T) {
+	device := &countingDevice{responses: []countedResponse{{503, unavailableResponse}}, answered: make(chan struct{}, 1)}
+	client := newTestClient(t, device)
+	client.MaxRetries = 3
+	client.MaxBackoff = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-device.answered
+		cancel()
+	}()
+	start := time.Now()
+	_, err := client.Op(ctx, "<show><system><info/></system></show>")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Op() error = %v, want %v", err, context.Canceled)
+	}
+	if time.Since(start) > time.Minute {
+		t.Error("Op() waited out the backoff after being cancelled")
+	}
+	if got := device.requests.Load(); got != 1 {
+		t.Errorf("Op() made %d requests, want 1", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for _, maxBackoff := range []time.Duration{time.Second, 30 * time.Second} {
+		for attempt := 0; attempt < 70; attempt++ {
+			want := minBackoff << uint(attempt)
+			if want <= 0 || want > maxBackoff {
+				want = maxBackoff
+			}
+			for i := 0; i < 20; i++ {
+				if got := backoff(attempt, maxBackoff); got < want/2 || got > want {
+					t.Fatalf("backoff(%d, %s) = %s, want between %s and %s", attempt, maxBackoff, got, want/2, want)
+				}
+			}
+		}
+	}
+	if got := backoff(0, time.Nanosecond); got != time.Nanosecond {
+		t.Errorf("backoff(0, 1ns) = %s, want 1ns", got)
+	}
+}
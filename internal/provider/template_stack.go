@@ -0,0 +1,66 @@
+package pansdwan
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// XML Response Structs
+type stackTemplates struct {
+	XMLName xml.Name `xml:"response"`
+	Result  struct {
+		Templates struct {
+			Member []string `xml:"member"`
+		} `xml:"templates"`
+	} `xml:"result"`
+}
+
+// stackMemberTemplates returns the templates of a template stack, highest
+// priority first.
+func stackMemberTemplates(ctx context.Context, client *APIClient, loc Location) ([]string, error) {
+	body, err := client.Get(ctx, loc.StackTemplatesXPath())
+	if errors.Is(err, ErrObjectNotPresent) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var resp stackTemplates
+	if err := decodeXML(body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result.Templates.Member, nil
+}
+
+// stackObjectNotPresent is called by Read when an object is missing from a
+// template stack. Stack xpaths only return values set on the stack itself, so
+// an object defined in one of the stack's templates is not the object the
+// resource manages. Report where it was found so the plan's recreate is not a
+// surprise.
+func stackObjectNotPresent(ctx context.Context, client *APIClient, loc Location, object string, xpath func(Location) string) diag.Diagnostics {
+	if loc.Type != LocationTemplateStack {
+		return nil
+	}
+	templates, err := stackMemberTemplates(ctx, client, loc)
+	if err != nil {
+		return diagFromErr(fmt.Sprintf("Failed to read templates of %s", loc), err)
+	}
+	for _, template := range templates {
+		_, err := client.Get(ctx, xpath(TemplateLocation(template)))
+		if errors.Is(err, ErrObjectNotPresent) {
+			continue
+		}
+		if err != nil {
+			return diagFromErr(fmt.Sprintf("Failed to read %s from template %q", object, template), err)
+		}
+		var diags diag.Diagnostics
+		diags.AddWarning(fmt.Sprintf("%s is defined in a member template, not the template stack", object),
+			fmt.Sprintf("%s was not found in %s but exists in its member template %q. The resource manages the value set on the stack, so it will be created there.", object, loc, template))
+		return diags
+	}
+	return nil
+}
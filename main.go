@@ -1,12 +1,47 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"log"
+
 	pansdwan "github.com/avidpontoon/terraform-provider-pansdwan/internal/provider"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6/tf6server"
+	"github.com/hashicorp/terraform-plugin-mux/tf5to6server"
+	"github.com/hashicorp/terraform-plugin-mux/tf6muxserver"
 )
 
 func main() {
-	plugin.Serve(&plugin.ServeOpts{
-		ProviderFunc: pansdwan.Provider,
-	})
+	var debug bool
+	flag.BoolVar(&debug, "debug", false, "set to true to run the provider with support for debuggers like delve")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	// The SDK provider speaks protocol 5, upgrade it so it can be muxed with the framework provider
+	upgradedSdkServer, err := tf5to6server.UpgradeServer(ctx, pansdwan.Provider().GRPCProvider)
+	if err != nil {
+		log.Fatal(err)
+	}
+	providers := []func() tfprotov6.ProviderServer{
+		func() tfprotov6.ProviderServer {
+			return upgradedSdkServer
+		},
+		providerserver.NewProtocol6(pansdwan.NewFrameworkProvider()),
+	}
+	muxServer, err := tf6muxserver.NewMuxServer(ctx, providers...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var serveOpts []tf6server.ServeOpt
+	if debug {
+		serveOpts = append(serveOpts, tf6server.WithManagedDebug())
+	}
+	err = tf6server.Serve("registry.terraform.io/avidpontoon/pansdwan", muxServer.ProviderServer, serveOpts...)
+	if err != nil {
+		log.Fatal(err)
+	}
 }